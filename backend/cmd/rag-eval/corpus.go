@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/repositories"
+	"rag-chat-system/internal/services"
+)
+
+// maxCorpusFileSize skips generated or vendored blobs in a fixture tree.
+const maxCorpusFileSize = 1 << 20
+
+// ingestCorpus indexes the text files under dir into a new scratch project
+// with the given settings and returns the project's ID and the number of
+// files indexed. Hidden files and directories are skipped. The ID is also
+// returned with an error once the project exists; the caller deletes it.
+func ingestCorpus(ctx context.Context, dir string, settings models.ProjectSettings, projectRepo *repositories.ProjectRepo, fileRepo *repositories.FileRepo, ingestSvc *services.IngestService) (string, int, error) {
+	project := &models.Project{ID: uuid.New().String(), Name: "rag-eval " + filepath.Base(dir)}
+	if err := projectRepo.Create(ctx, project); err != nil {
+		return "", 0, fmt.Errorf("create project: %w", err)
+	}
+	if err := projectRepo.UpdateSettings(ctx, project.ID, settings); err != nil {
+		return project.ID, 0, fmt.Errorf("project settings: %w", err)
+	}
+
+	files := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxCorpusFileSize {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.ContainsRune(content, 0) || !utf8.Valid(content) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		f := &models.File{ID: uuid.New().String(), ProjectID: project.ID, Name: d.Name(), Path: rel}
+		if err := fileRepo.Create(ctx, f); err != nil {
+			return fmt.Errorf("create file %s: %w", rel, err)
+		}
+		if err := ingestSvc.IngestContent(ctx, project.ID, f.ID, string(content), rel); err != nil {
+			return fmt.Errorf("ingest %s: %w", rel, err)
+		}
+		files++
+		return nil
+	})
+	return project.ID, files, err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"rag-chat-system/internal/models"
+)
+
+// goldenCase is one question of the golden set. A retrieved chunk is
+// relevant when its file is one of ExpectedPaths (matched as a path suffix)
+// or its content contains one of ExpectedSubstrings.
+type goldenCase struct {
+	Question           string              `json:"question"`
+	ExpectedPaths      []string            `json:"expected_paths"`
+	ExpectedSubstrings []string            `json:"expected_substrings"`
+	Filter             models.SearchFilter `json:"filter"`
+}
+
+// loadGolden reads a golden set from a YAML list (.yaml, .yml) or from JSON
+// lines (anything else).
+func loadGolden(path string) ([]goldenCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []goldenCase
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := decodeYAML(data, &cases); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			var c goldenCase
+			if err := json.Unmarshal([]byte(text), &c); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			cases = append(cases, c)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, c := range cases {
+		if strings.TrimSpace(c.Question) == "" {
+			return nil, fmt.Errorf("%s: case %d has no question", path, i+1)
+		}
+		if len(c.ExpectedPaths) == 0 && len(c.ExpectedSubstrings) == 0 {
+			return nil, fmt.Errorf("%s: case %d (%q) expects nothing", path, i+1, c.Question)
+		}
+	}
+	return cases, nil
+}
+
+// loadProfile overlays a settings profile (JSON or YAML, keyed like the
+// settings API) on base.
+func loadProfile(path string, base models.ProjectSettings) (models.ProjectSettings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+	settings := base
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = decodeYAML(data, &settings)
+	default:
+		err = json.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return base, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// decodeYAML decodes YAML into v through JSON, so the json tags of the
+// models apply to YAML keys as well.
+func decodeYAML(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,278 @@
+// Command rag-eval measures retrieval quality on a golden set of questions.
+//
+// Each question is run through RAGService against one indexed project and
+// scored by recall@k, MRR and nDCG@k. Passing -compare runs the set a second
+// time with another settings profile and prints both side by side. By
+// default queries are embedded with the deterministic hash embedder, so the
+// project must have been indexed with EMBEDDING_PROVIDER=hash; no network is
+// needed then.
+//
+//	go run ./cmd/rag-eval -project <id> -golden golden.yaml -k 10 \
+//		-profile current.yaml -compare candidate.yaml
+//
+// With -corpus instead of -project, a fixture tree is first ingested into a
+// scratch project with the -profile settings (chunking included) and the
+// chosen embedder, and the project is deleted afterwards. This only needs
+// Postgres, so it suits CI; set TIKTOKEN_CACHE_DIR to a directory holding
+// the cl100k_base encoding to avoid downloading it.
+//
+//	go run ./cmd/rag-eval -corpus cmd/rag-eval/testdata/corpus \
+//		-golden cmd/rag-eval/testdata/golden.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"rag-chat-system/internal/config"
+	"rag-chat-system/internal/database"
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/repositories"
+	"rag-chat-system/internal/services"
+	"rag-chat-system/internal/storage"
+)
+
+// profile is a named set of project settings to evaluate.
+type profile struct {
+	name     string
+	settings models.ProjectSettings
+}
+
+func main() {
+	projectID := flag.String("project", "", "ID of the indexed project to search")
+	corpusDir := flag.String("corpus", "", "directory to ingest into a scratch project instead of -project")
+	goldenPath := flag.String("golden", "", "golden set, YAML list or JSON lines (required)")
+	k := flag.Int("k", 10, "chunks retrieved and scored per question; overrides top_k")
+	profilePath := flag.String("profile", "", "settings profile to evaluate instead of the stored settings")
+	comparePath := flag.String("compare", "", "second settings profile to evaluate side by side")
+	embedderName := flag.String("embedder", "hash", `"hash" for the deterministic embedder or "config" for the server's`)
+	rerankerName := flag.String("reranker", "bm25", `"bm25" or "none"`)
+	strategy := flag.String("strategy", models.RetrievalSingle, "retrieval strategy; multi_query and hyde call the chat model")
+	verbose := flag.Bool("v", false, "print scores per question")
+	flag.Parse()
+
+	if (*projectID == "") == (*corpusDir == "") || *goldenPath == "" || *k < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cases, err := loadGolden(*goldenPath)
+	if err != nil {
+		log.Fatalf("Load golden set: %v", err)
+	}
+
+	cfg := config.Load()
+	pool := database.Connect(cfg.DatabaseURL())
+	defer pool.Close()
+	projectRepo := repositories.NewProjectRepo(pool)
+	chunkRepo := repositories.NewChunkRepo(pool)
+	symbolRepo := repositories.NewSymbolRepo(pool)
+	importRepo := repositories.NewImportRepo(pool)
+	fileRepo := repositories.NewFileRepo(pool)
+	embeddingSvc := newEmbeddingService(cfg, *embedderName)
+	// Referenced lines are read from the fixture tree itself with -corpus
+	var store storage.Storage
+	if *corpusDir != "" {
+		store = storage.NewLocalStorage(*corpusDir)
+	} else {
+		store = newStorage(cfg)
+	}
+	refSvc := services.NewReferenceService(fileRepo, store)
+	ragSvc := newRAGService(cfg, chunkRepo, symbolRepo, importRepo, projectRepo, refSvc, embeddingSvc, *rerankerName)
+
+	// The scratch project of -corpus is removed on every exit path
+	ctx := context.Background()
+	cleanup := func() {}
+	fatalf := func(format string, v ...interface{}) {
+		cleanup()
+		log.Fatalf(format, v...)
+	}
+
+	if *corpusDir != "" {
+		database.Migrate(pool, cfg.EmbeddingDimensions, cfg.EmbeddingReset)
+		settings := models.DefaultProjectSettings()
+		if *profilePath != "" {
+			if settings, err = loadProfile(*profilePath, settings); err != nil {
+				log.Fatalf("Load profile: %v", err)
+			}
+		}
+		ingestSvc := services.NewIngestService(chunkRepo, symbolRepo, importRepo, projectRepo, embeddingSvc,
+			services.NewEmbeddingCacheService(repositories.NewEmbeddingCacheRepo(pool)))
+		id, n, err := ingestCorpus(ctx, *corpusDir, settings, projectRepo, fileRepo, ingestSvc)
+		if id != "" {
+			cleanup = func() {
+				if err := projectRepo.Delete(context.Background(), id); err != nil {
+					log.Printf("Delete scratch project %s: %v", id, err)
+				}
+			}
+			defer cleanup()
+		}
+		if err != nil {
+			fatalf("Ingest %s: %v", *corpusDir, err)
+		}
+		log.Printf("Indexed %d files of %s", n, *corpusDir)
+		*projectID = id
+	}
+
+	stored, err := projectRepo.GetSettings(ctx, *projectID)
+	if err != nil {
+		fatalf("Project %s: %v", *projectID, err)
+	}
+	profiles := []profile{{name: "stored", settings: stored}}
+	if *profilePath != "" {
+		settings, err := loadProfile(*profilePath, stored)
+		if err != nil {
+			fatalf("Load profile: %v", err)
+		}
+		profiles[0] = profile{name: filepath.Base(*profilePath), settings: settings}
+	}
+	if *comparePath != "" {
+		settings, err := loadProfile(*comparePath, stored)
+		if err != nil {
+			fatalf("Load profile: %v", err)
+		}
+		profiles = append(profiles, profile{name: filepath.Base(*comparePath), settings: settings})
+	}
+
+	results := make([][]scores, len(profiles))
+	for i, p := range profiles {
+		p.settings.TopK = *k
+		settings := map[string]models.ProjectSettings{*projectID: p.settings}
+		for _, c := range cases {
+			trace, err := ragSvc.RetrieveWithSettings(ctx, c.Question, settings, *strategy, c.Filter)
+			if err != nil {
+				fatalf("[%s] %q: %v", p.name, c.Question, err)
+			}
+			results[i] = append(results[i], score(c, trace.Chunks, *k))
+		}
+	}
+
+	if *verbose {
+		printCases(cases, profiles, results)
+	}
+	printSummary(profiles, results, len(cases), *k)
+}
+
+// newEmbeddingService builds the embedder chosen on the command line.
+func newEmbeddingService(cfg *config.Config, embedderName string) *services.EmbeddingService {
+	var embedder services.Embedder
+	switch embedderName {
+	case "hash":
+		embedder = services.NewHashEmbedder(cfg.EmbeddingDimensions)
+	case "config":
+		switch cfg.EmbeddingProvider {
+		case "hash":
+			embedder = services.NewHashEmbedder(cfg.EmbeddingDimensions)
+		case "openai-compatible":
+			embedder = services.NewOpenAICompatibleEmbedder(cfg.EmbeddingBaseURL, cfg.EmbeddingAPIKey,
+				cfg.EmbeddingModel, cfg.EmbeddingDimensions, cfg.OpenAIMaxRetries)
+		default:
+			embedder = services.NewOpenAIEmbedder(services.NewOpenAIService(cfg.OpenAIKey, cfg.OpenAIMaxRetries).Client,
+				cfg.EmbeddingModel, cfg.EmbeddingDimensions)
+		}
+	default:
+		log.Fatalf("Unknown embedder %q", embedderName)
+	}
+	return services.NewEmbeddingService(embedder, cfg.EmbeddingBatchSize, cfg.EmbeddingWorkers)
+}
+
+// newStorage opens the server's file storage, which referenced lines are
+// read from.
+func newStorage(cfg *config.Config) storage.Storage {
+	if cfg.R2Endpoint != "" && cfg.R2AccessKeyID != "" && cfg.R2SecretAccessKey != "" && cfg.R2Bucket != "" {
+		s3Store, err := storage.NewS3Storage(cfg.R2AccessKeyID, cfg.R2SecretAccessKey, cfg.R2Endpoint, cfg.R2Bucket)
+		if err != nil {
+			log.Fatalf("Failed to initialize S3 storage: %v", err)
+		}
+		return s3Store
+	}
+	return storage.NewLocalStorage(cfg.StoragePath)
+}
+
+// newRAGService builds the retrieval pipeline the way the server does, with
+// the reranker chosen on the command line.
+func newRAGService(cfg *config.Config, chunkRepo *repositories.ChunkRepo, symbolRepo *repositories.SymbolRepo, importRepo *repositories.ImportRepo, projectRepo *repositories.ProjectRepo, refSvc *services.ReferenceService, embeddingSvc *services.EmbeddingService, rerankerName string) *services.RAGService {
+	var reranker services.Reranker
+	switch rerankerName {
+	case "none":
+	case "bm25":
+		reranker = services.NewLexicalReranker()
+	default:
+		log.Fatalf("Unknown reranker %q", rerankerName)
+	}
+
+	var chatModel services.ChatModel
+	if cfg.ChatProvider == "openai-compatible" {
+		chatModel = services.NewOpenAICompatibleChatModel(cfg.ChatBaseURL, cfg.ChatAPIKey, cfg.ChatModel, cfg.OpenAIMaxRetries)
+	} else {
+		chatModel = services.NewOpenAIChatModel(services.NewOpenAIService(cfg.OpenAIKey, cfg.OpenAIMaxRetries).Client, cfg.ChatModel)
+	}
+
+	return services.NewRAGService(chunkRepo, symbolRepo, services.NewGraphService(importRepo, chunkRepo), refSvc, projectRepo,
+		embeddingSvc, services.NewQueryExpander(chatModel, cfg.ChatModel), reranker, cfg.RerankCandidates, cfg.RetrievalTopK)
+}
+
+func printCases(cases []goldenCase, profiles []profile, results [][]scores) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "question")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "\t%s recall\tmrr\tndcg", p.name)
+	}
+	fmt.Fprintln(w)
+	for i, c := range cases {
+		q := []rune(c.Question)
+		if len(q) > 60 {
+			q = append(q[:57], []rune("...")...)
+		}
+		fmt.Fprint(w, string(q))
+		for j := range profiles {
+			s := results[j][i]
+			fmt.Fprintf(w, "\t%.3f\t%.3f\t%.3f", s.Recall, s.MRR, s.NDCG)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+func printSummary(profiles []profile, results [][]scores, n, k int) {
+	means := make([]scores, len(profiles))
+	for i := range profiles {
+		means[i] = mean(results[i])
+	}
+
+	fmt.Printf("%d questions, k=%d\n", n, k)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "metric\t")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t", p.name)
+	}
+	if len(profiles) == 2 {
+		fmt.Fprint(w, "delta\t")
+	}
+	fmt.Fprintln(w)
+
+	rows := []struct {
+		name string
+		get  func(scores) float64
+	}{
+		{fmt.Sprintf("recall@%d", k), func(s scores) float64 { return s.Recall }},
+		{"mrr", func(s scores) float64 { return s.MRR }},
+		{fmt.Sprintf("ndcg@%d", k), func(s scores) float64 { return s.NDCG }},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t", row.name)
+		for _, m := range means {
+			fmt.Fprintf(w, "%.3f\t", row.get(m))
+		}
+		if len(means) == 2 {
+			fmt.Fprintf(w, "%+.3f\t", row.get(means[1])-row.get(means[0]))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
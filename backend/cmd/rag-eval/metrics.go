@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	"rag-chat-system/internal/models"
+)
+
+// scores are the metrics of one question, or their means over a golden set.
+type scores struct {
+	Recall float64 // share of expected items found in the top k
+	MRR    float64 // reciprocal rank of the first relevant chunk
+	NDCG   float64 // binary-gain nDCG over the top k
+}
+
+// expectedItem is one expected path or substring of a golden case.
+type expectedItem struct {
+	path      string
+	substring string
+}
+
+func (e expectedItem) matches(c models.ChunkSearchResult) bool {
+	if e.path != "" {
+		p := strings.TrimPrefix(e.path, "/")
+		return c.FilePath == p || strings.HasSuffix(c.FilePath, "/"+p)
+	}
+	return strings.Contains(c.Content, e.substring)
+}
+
+// score rates the top k chunks retrieved for a case. A chunk counts as
+// relevant for nDCG only if it covers an expected item no higher-ranked
+// chunk covered, so repeated hits on one file are not rewarded.
+func score(c goldenCase, chunks []models.ChunkSearchResult, k int) scores {
+	var items []expectedItem
+	for _, p := range c.ExpectedPaths {
+		items = append(items, expectedItem{path: p})
+	}
+	for _, s := range c.ExpectedSubstrings {
+		items = append(items, expectedItem{substring: s})
+	}
+	if len(chunks) > k {
+		chunks = chunks[:k]
+	}
+
+	var s scores
+	found := make([]bool, len(items))
+	foundCount := 0
+	dcg := 0.0
+	for rank, chunk := range chunks {
+		relevant, novel := false, false
+		for i, item := range items {
+			if !item.matches(chunk) {
+				continue
+			}
+			relevant = true
+			if !found[i] {
+				found[i], novel = true, true
+				foundCount++
+			}
+		}
+		if relevant && s.MRR == 0 {
+			s.MRR = 1 / float64(rank+1)
+		}
+		if novel {
+			dcg += 1 / math.Log2(float64(rank+2))
+		}
+	}
+
+	ideal := 0.0
+	for rank := 0; rank < min(len(items), k); rank++ {
+		ideal += 1 / math.Log2(float64(rank+2))
+	}
+	s.Recall = float64(foundCount) / float64(len(items))
+	if ideal > 0 {
+		s.NDCG = dcg / ideal
+	}
+	return s
+}
+
+// mean averages per-question scores.
+func mean(all []scores) scores {
+	var m scores
+	if len(all) == 0 {
+		return m
+	}
+	for _, s := range all {
+		m.Recall += s.Recall
+		m.MRR += s.MRR
+		m.NDCG += s.NDCG
+	}
+	n := float64(len(all))
+	return scores{Recall: m.Recall / n, MRR: m.MRR / n, NDCG: m.NDCG / n}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"rag-chat-system/internal/models"
+)
+
+func chunksAt(paths ...string) []models.ChunkSearchResult {
+	chunks := make([]models.ChunkSearchResult, len(paths))
+	for i, p := range paths {
+		chunks[i] = models.ChunkSearchResult{FilePath: p}
+	}
+	return chunks
+}
+
+// gain is the discounted gain of a relevant chunk at a 0-based rank.
+func gain(rank int) float64 {
+	return 1 / math.Log2(float64(rank+2))
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		c      goldenCase
+		chunks []models.ChunkSearchResult
+		k      int
+		want   scores
+	}{
+		{
+			name:   "perfect ranking",
+			c:      goldenCase{ExpectedPaths: []string{"a.go", "b.go"}},
+			chunks: chunksAt("a.go", "b.go", "c.go"),
+			k:      3,
+			want:   scores{Recall: 1, MRR: 1, NDCG: 1},
+		},
+		{
+			name:   "first hit second",
+			c:      goldenCase{ExpectedPaths: []string{"a.go", "b.go"}},
+			chunks: chunksAt("x.go", "a.go", "b.go"),
+			k:      3,
+			want:   scores{Recall: 1, MRR: 0.5, NDCG: (gain(1) + gain(2)) / (gain(0) + gain(1))},
+		},
+		{
+			name:   "repeated hits on one file are not rewarded",
+			c:      goldenCase{ExpectedPaths: []string{"a.go", "b.go"}},
+			chunks: chunksAt("a.go", "a.go", "b.go"),
+			k:      3,
+			want:   scores{Recall: 1, MRR: 1, NDCG: (gain(0) + gain(2)) / (gain(0) + gain(1))},
+		},
+		{
+			name:   "hits below k do not count",
+			c:      goldenCase{ExpectedPaths: []string{"a.go"}},
+			chunks: chunksAt("x.go", "y.go", "a.go"),
+			k:      2,
+			want:   scores{},
+		},
+		{
+			name:   "partial recall",
+			c:      goldenCase{ExpectedPaths: []string{"a.go", "b.go"}},
+			chunks: chunksAt("b.go", "x.go"),
+			k:      2,
+			want:   scores{Recall: 0.5, MRR: 1, NDCG: gain(0) / (gain(0) + gain(1))},
+		},
+		{
+			name:   "paths match on whole trailing segments",
+			c:      goldenCase{ExpectedPaths: []string{"/internal/a.go"}},
+			chunks: chunksAt("notinternal/a.go", "backend/internal/a.go"),
+			k:      2,
+			want:   scores{Recall: 1, MRR: 0.5, NDCG: gain(1) / gain(0)},
+		},
+		{
+			name: "substrings match chunk content",
+			c:    goldenCase{ExpectedSubstrings: []string{"func Retrieve("}},
+			chunks: []models.ChunkSearchResult{
+				{FilePath: "a.go", Content: "func Search() {}"},
+				{FilePath: "b.go", Content: "func Retrieve(ctx context.Context) {}"},
+			},
+			k:    2,
+			want: scores{Recall: 1, MRR: 0.5, NDCG: gain(1) / gain(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := score(tt.c, tt.chunks, tt.k)
+			if !closeScores(got, tt.want) {
+				t.Errorf("score() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMean(t *testing.T) {
+	tests := []struct {
+		name string
+		all  []scores
+		want scores
+	}{
+		{name: "empty", all: nil, want: scores{}},
+		{
+			name: "averages each metric",
+			all:  []scores{{Recall: 1, MRR: 1, NDCG: 1}, {Recall: 0.5, MRR: 0, NDCG: 0.25}},
+			want: scores{Recall: 0.75, MRR: 0.5, NDCG: 0.625},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mean(tt.all); !closeScores(got, tt.want) {
+				t.Errorf("mean() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func closeScores(a, b scores) bool {
+	const eps = 1e-9
+	return math.Abs(a.Recall-b.Recall) < eps && math.Abs(a.MRR-b.MRR) < eps && math.Abs(a.NDCG-b.NDCG) < eps
+}
@@ -0,0 +1,23 @@
+package auth
+
+import "time"
+
+// SessionTTL is how long a login session stays valid without activity.
+const SessionTTL = 30 * time.Minute
+
+// Session is a logged-in user's server-side session.
+type Session struct {
+	ID        string
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the session has passed its expiry time.
+func (s *Session) Expired(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
+
+// Refresh extends the session by SessionTTL from now.
+func (s *Session) Refresh(now time.Time) {
+	s.ExpiresAt = now.Add(SessionTTL)
+}
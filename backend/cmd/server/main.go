@@ -11,6 +11,7 @@ import (
 	"rag-chat-system/internal/config"
 	"rag-chat-system/internal/database"
 	"rag-chat-system/internal/handlers"
+	"rag-chat-system/internal/rag"
 	"rag-chat-system/internal/repositories"
 	"rag-chat-system/internal/services"
 	"rag-chat-system/internal/storage"
@@ -21,14 +22,19 @@ func main() {
 
 	// Database
 	pool := database.Connect(cfg.DatabaseURL())
-	database.Migrate(pool)
+	database.Migrate(pool, cfg.EmbeddingDimensions, cfg.EmbeddingReset)
 
 	// Repositories
 	projectRepo := repositories.NewProjectRepo(pool)
 	fileRepo := repositories.NewFileRepo(pool)
 	chunkRepo := repositories.NewChunkRepo(pool)
+	symbolRepo := repositories.NewSymbolRepo(pool)
+	importRepo := repositories.NewImportRepo(pool)
 	chatRepo := repositories.NewChatRepo(pool)
 	messageRepo := repositories.NewMessageRepo(pool)
+	messageTraceRepo := repositories.NewMessageTraceRepo(pool)
+	embeddingCacheRepo := repositories.NewEmbeddingCacheRepo(pool)
+	summaryCacheRepo := repositories.NewSummaryCacheRepo(pool)
 
 	// Storage
 	var store storage.Storage
@@ -56,25 +62,76 @@ func main() {
 	}
 
 	// Services
-	openaiSvc := services.NewOpenAIService(cfg.OpenAIKey)
-	embeddingSvc := services.NewEmbeddingService(openaiSvc)
-	ragSvc := services.NewRAGService(chunkRepo, embeddingSvc)
-	ingestSvc := services.NewIngestService(chunkRepo, embeddingSvc)
-	fileSvc := services.NewFileService(fileRepo, chunkRepo, ingestSvc, store)
-	chatSvc := services.NewChatService(chatRepo, messageRepo, ragSvc, openaiSvc)
+	openaiSvc := services.NewOpenAIService(cfg.OpenAIKey, cfg.OpenAIMaxRetries)
+	var embedder services.Embedder
+	switch cfg.EmbeddingProvider {
+	case "hash":
+		embedder = services.NewHashEmbedder(cfg.EmbeddingDimensions)
+	case "openai-compatible":
+		if cfg.EmbeddingBaseURL == "" {
+			log.Fatal("EMBEDDING_BASE_URL is required for the openai-compatible embedding provider")
+		}
+		embedder = services.NewOpenAICompatibleEmbedder(cfg.EmbeddingBaseURL, cfg.EmbeddingAPIKey,
+			cfg.EmbeddingModel, cfg.EmbeddingDimensions, cfg.OpenAIMaxRetries)
+	default:
+		embedder = services.NewOpenAIEmbedder(openaiSvc.Client, cfg.EmbeddingModel, cfg.EmbeddingDimensions)
+	}
+	log.Printf("Embedding model: %s (%d dimensions)", embedder.Model(), embedder.Dimensions())
+	embeddingSvc := services.NewEmbeddingService(embedder, cfg.EmbeddingBatchSize, cfg.EmbeddingWorkers)
+	embeddingCacheSvc := services.NewEmbeddingCacheService(embeddingCacheRepo)
+	var chatModel services.ChatModel
+	switch cfg.ChatProvider {
+	case "openai-compatible":
+		if cfg.ChatBaseURL == "" {
+			log.Fatal("CHAT_BASE_URL is required for the openai-compatible chat provider")
+		}
+		chatModel = services.NewOpenAICompatibleChatModel(cfg.ChatBaseURL, cfg.ChatAPIKey, cfg.ChatModel, cfg.OpenAIMaxRetries)
+	default:
+		chatModel = services.NewOpenAIChatModel(openaiSvc.Client, cfg.ChatModel)
+	}
+
+	var reranker services.Reranker
+	switch cfg.Reranker {
+	case "none":
+	case "llm":
+		reranker = services.NewLLMReranker(chatModel, cfg.ChatModel)
+	default:
+		reranker = services.NewLexicalReranker()
+	}
+
+	queryExpander := services.NewQueryExpander(chatModel, cfg.ChatModel)
+	graphSvc := services.NewGraphService(importRepo, chunkRepo)
+	refSvc := services.NewReferenceService(fileRepo, store)
+	ragSvc := services.NewRAGService(chunkRepo, symbolRepo, graphSvc, refSvc, projectRepo, embeddingSvc, queryExpander, reranker, cfg.RerankCandidates, cfg.RetrievalTopK)
+	ingestSvc := services.NewIngestService(chunkRepo, symbolRepo, importRepo, projectRepo, embeddingSvc, embeddingCacheSvc)
+	if err := ingestSvc.BackfillFTS(context.Background()); err != nil {
+		log.Printf("Full-text index backfill not started: %v", err)
+	}
+	summarySvc := services.NewSummaryService(chunkRepo, fileRepo, projectRepo, summaryCacheRepo, embeddingSvc, embeddingCacheSvc, chatModel, cfg.ChatModel)
+	fileSvc := services.NewFileService(fileRepo, chunkRepo, ingestSvc, summarySvc, store)
+	queryRewriter := services.NewQueryRewriter(chatModel, cfg.ChatModel)
+	verifier := services.NewVerifier(chatModel, cfg.ChatModel, fileRepo, chunkRepo, symbolRepo, projectRepo)
+	chatSvc := services.NewChatService(chatRepo, messageRepo, messageTraceRepo, ragSvc, summarySvc, verifier, queryRewriter, chatModel, cfg.ChatModel, rag.PromptBudget{
+		Total:        cfg.ContextTokenBudget,
+		ContextShare: cfg.ContextChunkShare,
+	})
+	chatSvc.StartTraceRetention(cfg.TraceRetentionDays)
 	gitSvc := services.NewGitService(projectRepo, fileRepo, chunkRepo, fileSvc, cfg.GitEncryptionKey)
 
 	// Handlers
-	projectHandler := handlers.NewProjectHandler(projectRepo)
+	projectHandler := handlers.NewProjectHandler(projectRepo, ingestSvc, summarySvc)
 	fileHandler := handlers.NewFileHandler(fileSvc)
 	chatHandler := handlers.NewChatHandler(chatSvc)
 	gitHandler := handlers.NewGitHandler(gitSvc)
+	adminHandler := handlers.NewAdminHandler(embeddingCacheSvc)
+	graphHandler := handlers.NewGraphHandler(graphSvc)
+	searchHandler := handlers.NewSearchHandler(ragSvc, services.NewGrepService(chunkRepo), symbolRepo)
 
 	// Echo
 	e := echo.New()
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{"*"},
 	}))
 	e.Use(middleware.Logger())
@@ -83,11 +140,19 @@ func main() {
 	e.POST("/projects", projectHandler.Create)
 	e.GET("/projects", projectHandler.List)
 	e.DELETE("/projects/:id", projectHandler.Delete)
+	e.GET("/projects/:id/settings", projectHandler.GetSettings)
+	e.PUT("/projects/:id/settings", projectHandler.UpdateSettings)
+	e.POST("/projects/search", searchHandler.Search)
+	e.GET("/projects/:id/grep", searchHandler.Grep)
+	e.GET("/projects/:id/symbols", searchHandler.Symbols)
+	e.GET("/projects/:id/graph", graphHandler.GetGraph)
 
 	// Files
 	e.POST("/projects/:id/upload-file", fileHandler.UploadFile)
 	e.POST("/projects/:id/upload-folder", fileHandler.UploadFolder)
 	e.GET("/projects/:id/files", fileHandler.ListFiles)
+	e.POST("/projects/:id/reindex", fileHandler.ReindexProject)
+	e.GET("/projects/:id/reindex", fileHandler.GetReindexStatus)
 	e.DELETE("/files/:id", fileHandler.DeleteFile)
 
 	// Git
@@ -99,10 +164,16 @@ func main() {
 	// Chats
 	e.POST("/chats", chatHandler.CreateChat)
 	e.GET("/chats", chatHandler.ListChats)
+	e.PATCH("/chats/:id", chatHandler.UpdateChat)
 	e.DELETE("/chats/:id", chatHandler.DeleteChat)
 	e.PUT("/chats/:id/projects", chatHandler.UpdateChatProjects)
 	e.GET("/chats/:id/messages", chatHandler.GetMessages)
 	e.POST("/chats/:id/messages", chatHandler.SendMessage)
+	e.GET("/messages/:id/trace", chatHandler.GetMessageTrace)
+
+	// Admin
+	e.GET("/admin/embedding-cache", adminHandler.EmbeddingCacheStats)
+	e.POST("/admin/embedding-cache/prune", adminHandler.PruneEmbeddingCache)
 
 	log.Fatal(e.Start(":" + cfg.BackendPort))
 }
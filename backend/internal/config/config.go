@@ -1,6 +1,9 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 type Config struct {
 	OpenAIKey   string
@@ -19,6 +22,39 @@ type Config struct {
 
 	BackendPort      string
 	GitEncryptionKey string
+
+	// Chat model
+	ChatProvider string // "openai" or "openai-compatible"
+	ChatModel    string
+	ChatBaseURL  string
+	ChatAPIKey   string
+
+	// Retrieval
+	Reranker         string // "bm25", "llm" or "none"
+	RerankCandidates int
+	RetrievalTopK    int
+
+	// Prompt assembly
+	ContextTokenBudget int     // tokens for system prompt, context, history and question
+	ContextChunkShare  float64 // share of the budget left after system+question given to chunks
+
+	// Message traces older than this many days are deleted; 0 keeps them
+	TraceRetentionDays int
+
+	// Embeddings
+	EmbeddingProvider   string // "openai", "openai-compatible" or "hash"
+	EmbeddingModel      string
+	EmbeddingBaseURL    string
+	EmbeddingAPIKey     string
+	EmbeddingDimensions int
+	// Allows startup to clear stored vectors when EmbeddingDimensions no
+	// longer matches the schema
+	EmbeddingReset bool
+
+	// OpenAI request tuning
+	OpenAIMaxRetries   int
+	EmbeddingBatchSize int
+	EmbeddingWorkers   int
 }
 
 func Load() *Config {
@@ -39,6 +75,31 @@ func Load() *Config {
 
 		BackendPort:      getEnv("BACKEND_PORT", "8080"),
 		GitEncryptionKey: getEnv("GIT_ENCRYPTION_KEY", ""),
+
+		ChatProvider: getEnv("CHAT_PROVIDER", "openai"),
+		ChatModel:    getEnv("CHAT_MODEL", "gpt-4o-mini"),
+		ChatBaseURL:  getEnv("CHAT_BASE_URL", ""),
+		ChatAPIKey:   getEnv("CHAT_API_KEY", getEnv("OPENAI_API_KEY", "")),
+
+		Reranker:         getEnv("RERANKER", "bm25"),
+		RerankCandidates: getEnvInt("RERANK_CANDIDATES", 50),
+		RetrievalTopK:    getEnvInt("RETRIEVAL_TOP_K", 8),
+
+		ContextTokenBudget: getEnvInt("CONTEXT_TOKEN_BUDGET", 12000),
+		ContextChunkShare:  getEnvFloat("CONTEXT_CHUNK_SHARE", 0.6),
+
+		TraceRetentionDays: getEnvInt("TRACE_RETENTION_DAYS", 30),
+
+		EmbeddingProvider:   getEnv("EMBEDDING_PROVIDER", "openai"),
+		EmbeddingModel:      getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		EmbeddingBaseURL:    getEnv("EMBEDDING_BASE_URL", ""),
+		EmbeddingAPIKey:     getEnv("EMBEDDING_API_KEY", getEnv("OPENAI_API_KEY", "")),
+		EmbeddingDimensions: getEnvInt("EMBEDDING_DIMENSIONS", 1536),
+		EmbeddingReset:      getEnv("EMBEDDING_RESET", "") == "true",
+
+		OpenAIMaxRetries:   getEnvInt("OPENAI_MAX_RETRIES", 5),
+		EmbeddingBatchSize: getEnvInt("EMBEDDING_BATCH_SIZE", 64),
+		EmbeddingWorkers:   getEnvInt("EMBEDDING_WORKERS", 4),
 	}
 }
 
@@ -55,3 +116,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
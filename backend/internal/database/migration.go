@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func Migrate(pool *pgxpool.Pool) {
+// Migrate creates or updates the schema. embeddingDims sets the size of the
+// vector columns and must match the configured embedder.
+func Migrate(pool *pgxpool.Pool, embeddingDims int, embeddingReset bool) {
 	ctx := context.Background()
 
 	queries := []string{
@@ -35,7 +38,7 @@ func Migrate(pool *pgxpool.Pool) {
 			project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
 			file_id UUID REFERENCES files(id) ON DELETE CASCADE,
 			content TEXT,
-			embedding VECTOR(1536)
+			embedding VECTOR(` + strconv.Itoa(embeddingDims) + `)
 		)`,
 
 		`CREATE TABLE IF NOT EXISTS chats (
@@ -58,9 +61,23 @@ func Migrate(pool *pgxpool.Pool) {
 		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS git_token_encrypted TEXT`,
 		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP`,
 
+		// Per-project retrieval settings
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}'`,
+
 		// Chat project selection
 		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS project_ids TEXT[] DEFAULT '{}'`,
 
+		// Per-chat model selection
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS model TEXT`,
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS temperature REAL`,
+
+		// Conversation-aware query rewriting
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS query_rewrite BOOLEAN DEFAULT TRUE`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS rewritten_query TEXT`,
+
+		// Per-chat retrieval strategy
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS retrieval_strategy TEXT DEFAULT 'single'`,
+
 		// HNSW index for fast vector similarity search
 		`CREATE INDEX IF NOT EXISTS idx_document_chunks_embedding ON document_chunks USING hnsw (embedding vector_cosine_ops)`,
 
@@ -75,6 +92,99 @@ func Migrate(pool *pgxpool.Pool) {
 		// Metadata columns for source attribution
 		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS file_name TEXT`,
 		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS file_ext TEXT`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS file_path TEXT`,
+		`UPDATE document_chunks c SET file_path = COALESCE(p.path || '/', '') || f.name
+		 FROM files f LEFT JOIN files p ON p.id = f.parent_id
+		 WHERE f.id = c.file_id AND c.file_path IS NULL`,
+
+		// Symbol metadata from the code-aware chunker
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS symbol_name TEXT`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS symbol_kind TEXT`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS start_line INT`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS end_line INT`,
+
+		// Embedding cache keyed by hash of (model, chunk text)
+		`CREATE TABLE IF NOT EXISTS embedding_cache (
+			content_hash TEXT PRIMARY KEY,
+			model TEXT NOT NULL,
+			embedding VECTOR(` + strconv.Itoa(embeddingDims) + `) NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		)`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS content_hash TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_document_chunks_content_hash ON document_chunks (content_hash)`,
+
+		// Model that produced each chunk's embedding; earlier chunks all came from text-embedding-3-small
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS embedding_model TEXT`,
+		`UPDATE document_chunks SET embedding_model = 'text-embedding-3-small' WHERE embedding_model IS NULL AND embedding IS NOT NULL`,
+
+		// Citations saved with assistant messages
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS citations JSONB`,
+
+		// Chunk position within its file, for neighbor expansion
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS ordinal INT`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS start_byte INT`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS end_byte INT`,
+		`CREATE INDEX IF NOT EXISTS idx_document_chunks_file_ordinal ON document_chunks (file_id, ordinal)`,
+
+		// Text search configuration each tsvector was built with; NULL for
+		// chunks indexed before identifiers were pre-tokenized
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS fts_config TEXT`,
+
+		// Retrieval and prompt trace of each assistant message
+		`CREATE TABLE IF NOT EXISTS message_traces (
+			message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+			trace JSONB NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_message_traces_created_at ON message_traces (created_at)`,
+
+		// Trigram index for exact and regex code search
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_document_chunks_content_trgm ON document_chunks USING gin (content gin_trgm_ops)`,
+
+		// Declarations found in indexed source files
+		`CREATE TABLE IF NOT EXISTS symbols (
+			id UUID PRIMARY KEY,
+			project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+			file_id UUID REFERENCES files(id) ON DELETE CASCADE,
+			file_path TEXT NOT NULL,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			container TEXT,
+			signature TEXT,
+			start_line INT NOT NULL,
+			end_line INT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_symbols_project_name ON symbols (project_id, name)`,
+		`CREATE INDEX IF NOT EXISTS idx_symbols_file_id ON symbols (file_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_symbols_name_trgm ON symbols USING gin (name gin_trgm_ops)`,
+
+		// Import statements of indexed files, resolved into the dependency graph on read
+		`CREATE TABLE IF NOT EXISTS file_imports (
+			project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+			file_id UUID REFERENCES files(id) ON DELETE CASCADE,
+			file_path TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			target TEXT NOT NULL,
+			line INT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_file_imports_project_id ON file_imports (project_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_file_imports_file_id ON file_imports (file_id)`,
+
+		// Generated file and directory summaries are stored as chunks of their
+		// own type; source_hash identifies the text a summary was written from
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS chunk_type TEXT NOT NULL DEFAULT 'content'`,
+		`ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS source_hash TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_document_chunks_project_type ON document_chunks (project_id, chunk_type)`,
+		`CREATE TABLE IF NOT EXISTS summary_cache (
+			input_hash TEXT PRIMARY KEY,
+			summary TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		)`,
+
+		// Answer verification: opt-in per chat, result stored on the message
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS verify BOOLEAN DEFAULT FALSE`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS verification JSONB`,
 	}
 
 	for _, q := range queries {
@@ -83,5 +193,44 @@ func Migrate(pool *pgxpool.Pool) {
 		}
 	}
 
+	alignEmbeddingDimensions(ctx, pool, embeddingDims, embeddingReset)
+
 	fmt.Println("Migrations complete")
 }
+
+// alignEmbeddingDimensions resizes the vector columns when the configured
+// embedder's dimension differs from the existing schema. Stored vectors cannot
+// be converted, so they are cleared; projects must be re-synced or re-uploaded.
+// Since that loses the whole index, it only happens when reset is set;
+// otherwise a mismatch stops startup.
+func alignEmbeddingDimensions(ctx context.Context, pool *pgxpool.Pool, dims int, reset bool) {
+	var current int
+	err := pool.QueryRow(ctx,
+		`SELECT atttypmod FROM pg_attribute WHERE attrelid = 'document_chunks'::regclass AND attname = 'embedding'`,
+	).Scan(&current)
+	if err != nil {
+		log.Fatalf("Migration failed: read embedding dimensions: %v", err)
+	}
+	if current == dims {
+		return
+	}
+	if !reset {
+		log.Fatalf("Migration failed: stored embeddings have %d dimensions but EMBEDDING_DIMENSIONS is %d; "+
+			"fix EMBEDDING_DIMENSIONS, or set EMBEDDING_RESET=true to clear all stored vectors and re-embed", current, dims)
+	}
+
+	log.Printf("Embedding dimensions changed from %d to %d: clearing stored vectors, re-sync projects to re-embed", current, dims)
+	queries := []string{
+		`DROP INDEX IF EXISTS idx_document_chunks_embedding`,
+		fmt.Sprintf(`ALTER TABLE document_chunks ALTER COLUMN embedding TYPE VECTOR(%d) USING NULL`, dims),
+		`UPDATE document_chunks SET embedding_model = NULL`,
+		`DELETE FROM embedding_cache`,
+		fmt.Sprintf(`ALTER TABLE embedding_cache ALTER COLUMN embedding TYPE VECTOR(%d) USING NULL`, dims),
+		`CREATE INDEX IF NOT EXISTS idx_document_chunks_embedding ON document_chunks USING hnsw (embedding vector_cosine_ops)`,
+	}
+	for _, q := range queries {
+		if _, err := pool.Exec(ctx, q); err != nil {
+			log.Fatalf("Migration failed: %v\nQuery: %s", err, q)
+		}
+	}
+}
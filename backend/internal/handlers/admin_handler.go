@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"rag-chat-system/internal/services"
+)
+
+type AdminHandler struct {
+	cacheSvc *services.EmbeddingCacheService
+}
+
+func NewAdminHandler(cacheSvc *services.EmbeddingCacheService) *AdminHandler {
+	return &AdminHandler{cacheSvc: cacheSvc}
+}
+
+// EmbeddingCacheStats returns hit/miss counters and the number of cached embeddings.
+// GET /admin/embedding-cache
+func (h *AdminHandler) EmbeddingCacheStats(c echo.Context) error {
+	stats, err := h.cacheSvc.Stats(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// PruneEmbeddingCache deletes cached embeddings that no chunk references.
+// POST /admin/embedding-cache/prune
+func (h *AdminHandler) PruneEmbeddingCache(c echo.Context) error {
+	deleted, err := h.cacheSvc.Prune(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"deleted": deleted})
+}
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 
+	"rag-chat-system/internal/models"
 	"rag-chat-system/internal/services"
 )
 
@@ -21,16 +28,80 @@ func (h *ChatHandler) CreateChat(c echo.Context) error {
 	var req struct {
 		Title      string   `json:"title"`
 		ProjectIDs []string `json:"project_ids"`
+		services.ChatOptions
 	}
 	_ = c.Bind(&req)
+	if msg := validateChatOptions(req.ChatOptions); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
 
-	chat, err := h.chatSvc.CreateChat(c.Request().Context(), req.Title, req.ProjectIDs)
+	chat, err := h.chatSvc.CreateChat(c.Request().Context(), req.Title, req.ProjectIDs, req.ChatOptions)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 	return c.JSON(http.StatusCreated, chat)
 }
 
+// UpdateChat changes a chat's title or generation and retrieval settings.
+// PATCH /chats/:id
+func (h *ChatHandler) UpdateChat(c echo.Context) error {
+	chatID := c.Param("id")
+	var req struct {
+		Title *string `json:"title"`
+		services.ChatOptions
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	if msg := validateChatOptions(req.ChatOptions); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	chat, err := h.chatSvc.UpdateChat(c.Request().Context(), chatID, req.Title, req.ChatOptions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "chat not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, chat)
+}
+
+// validateChatOptions returns an error message for out-of-range settings, or "".
+func validateChatOptions(opts services.ChatOptions) string {
+	if opts.Model != nil && *opts.Model == "" {
+		return "model must not be empty"
+	}
+	if opts.Temperature != nil && (*opts.Temperature < 0 || *opts.Temperature > 2) {
+		return "temperature must be between 0 and 2"
+	}
+	if opts.RetrievalStrategy != nil {
+		switch *opts.RetrievalStrategy {
+		case models.RetrievalSingle, models.RetrievalMultiQuery, models.RetrievalHyDE:
+		default:
+			return "retrieval_strategy must be single, multi_query or hyde"
+		}
+	}
+	return ""
+}
+
+// validateSearchFilter returns an error message for a malformed filter, or "".
+func validateSearchFilter(f models.SearchFilter) string {
+	for _, id := range f.FileIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Sprintf("file_ids: %q is not a valid id", id)
+		}
+	}
+	for _, list := range [][]string{f.Paths, f.ExcludePaths, f.Exts, f.ExcludeExts} {
+		for _, v := range list {
+			if strings.TrimSpace(v) == "" {
+				return "filter entries must not be empty"
+			}
+		}
+	}
+	return ""
+}
+
 func (h *ChatHandler) ListChats(c echo.Context) error {
 	chats, err := h.chatSvc.ListChats(c.Request().Context())
 	if err != nil {
@@ -48,6 +119,19 @@ func (h *ChatHandler) GetMessages(c echo.Context) error {
 	return c.JSON(http.StatusOK, msgs)
 }
 
+// GetMessageTrace returns the retrieval and prompt trace of an assistant message.
+// GET /messages/:id/trace
+func (h *ChatHandler) GetMessageTrace(c echo.Context) error {
+	trace, err := h.chatSvc.GetMessageTrace(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "trace not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, trace)
+}
+
 func (h *ChatHandler) DeleteChat(c echo.Context) error {
 	chatID := c.Param("id")
 	if err := h.chatSvc.DeleteChat(c.Request().Context(), chatID); err != nil {
@@ -74,12 +158,16 @@ func (h *ChatHandler) SendMessage(c echo.Context) error {
 	chatID := c.Param("id")
 
 	var req struct {
-		Message    string   `json:"message"`
-		ProjectIDs []string `json:"project_ids"`
+		Message    string              `json:"message"`
+		ProjectIDs []string            `json:"project_ids"`
+		Filter     models.SearchFilter `json:"filter"`
 	}
 	if err := c.Bind(&req); err != nil || req.Message == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "message is required"})
 	}
+	if msg := validateSearchFilter(req.Filter); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
 
 	ctx := c.Request().Context()
 
@@ -89,7 +177,7 @@ func (h *ChatHandler) SendMessage(c echo.Context) error {
 	c.Response().Header().Set("Connection", "keep-alive")
 	c.Response().WriteHeader(http.StatusOK)
 
-	tokenCh, errCh := h.chatSvc.SendMessage(ctx, chatID, req.Message, req.ProjectIDs)
+	eventCh, errCh := h.chatSvc.SendMessage(ctx, chatID, req.Message, req.ProjectIDs, req.Filter)
 
 	flusher, ok := c.Response().Writer.(http.Flusher)
 	if !ok {
@@ -98,7 +186,7 @@ func (h *ChatHandler) SendMessage(c echo.Context) error {
 
 	for {
 		select {
-		case token, ok := <-tokenCh:
+		case event, ok := <-eventCh:
 			if !ok {
 				// Check for errors
 				select {
@@ -113,7 +201,7 @@ func (h *ChatHandler) SendMessage(c echo.Context) error {
 				flusher.Flush()
 				return nil
 			}
-			fmt.Fprintf(c.Response().Writer, "data: %s\n\n", token)
+			writeStreamEvent(c.Response().Writer, event)
 			flusher.Flush()
 
 		case err := <-errCh:
@@ -125,3 +213,24 @@ func (h *ChatHandler) SendMessage(c echo.Context) error {
 		}
 	}
 }
+
+// writeStreamEvent writes a single SSE data line. Non-token events are sent
+// in-band with a bracketed prefix, like [DONE] and [ERROR].
+func writeStreamEvent(w io.Writer, event services.StreamEvent) {
+	switch event.Type {
+	case "citations":
+		payload, err := json.Marshal(event.Citations)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: [CITATIONS] %s\n\n", payload)
+	case "verification":
+		payload, err := json.Marshal(event.Verification)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: [VERIFICATION] %s\n\n", payload)
+	default:
+		fmt.Fprintf(w, "data: %s\n\n", event.Token)
+	}
+}
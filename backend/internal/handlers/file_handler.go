@@ -45,6 +45,7 @@ func (h *FileHandler) UploadFile(c echo.Context) error {
 		log.Printf("[FileHandler] Failed to upload file to storage: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	h.fileSvc.SummarizeAsync(projectID)
 
 	return c.JSON(http.StatusOK, f)
 }
@@ -142,6 +143,8 @@ func (h *FileHandler) UploadFolder(c echo.Context) error {
 		}
 	}
 
+	h.fileSvc.SummarizeAsync(projectID)
+
 	return c.JSON(http.StatusOK, map[string]string{"status": "folder uploaded"})
 }
 
@@ -197,3 +200,24 @@ func (h *FileHandler) DeleteFile(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
 }
+
+// ReindexProject re-chunks and re-embeds all files of a project in the
+// background, e.g. after its chunking settings changed.
+// POST /projects/:id/reindex
+func (h *FileHandler) ReindexProject(c echo.Context) error {
+	projectID := c.Param("id")
+	if err := h.fileSvc.ReindexAsync(projectID); err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, map[string]string{"status": "reindexing"})
+}
+
+// GetReindexStatus reports the progress of the last reindex of a project.
+// GET /projects/:id/reindex
+func (h *FileHandler) GetReindexStatus(c echo.Context) error {
+	st := h.fileSvc.GetReindexStatus(c.Param("id"))
+	if st == nil {
+		return c.JSON(http.StatusOK, map[string]string{"status": "idle"})
+	}
+	return c.JSON(http.StatusOK, st)
+}
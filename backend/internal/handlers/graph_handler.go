@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"rag-chat-system/internal/services"
+)
+
+type GraphHandler struct {
+	graphSvc *services.GraphService
+}
+
+func NewGraphHandler(graphSvc *services.GraphService) *GraphHandler {
+	return &GraphHandler{graphSvc: graphSvc}
+}
+
+// GetGraph returns the file-level import graph of a project: one node per
+// indexed file and one edge per resolved import.
+// GET /projects/:id/graph
+func (h *GraphHandler) GetGraph(c echo.Context) error {
+	graph, err := h.graphSvc.Graph(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, graph)
+}
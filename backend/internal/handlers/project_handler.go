@@ -1,21 +1,28 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 
 	"rag-chat-system/internal/models"
 	"rag-chat-system/internal/repositories"
+	"rag-chat-system/internal/services"
 )
 
 type ProjectHandler struct {
-	repo *repositories.ProjectRepo
+	repo       *repositories.ProjectRepo
+	ingestSvc  *services.IngestService
+	summarySvc *services.SummaryService
 }
 
-func NewProjectHandler(repo *repositories.ProjectRepo) *ProjectHandler {
-	return &ProjectHandler{repo: repo}
+func NewProjectHandler(repo *repositories.ProjectRepo, ingestSvc *services.IngestService, summarySvc *services.SummaryService) *ProjectHandler {
+	return &ProjectHandler{repo: repo, ingestSvc: ingestSvc, summarySvc: summarySvc}
 }
 
 func (h *ProjectHandler) Create(c echo.Context) error {
@@ -55,3 +62,106 @@ func (h *ProjectHandler) Delete(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
 }
+
+// GetSettings returns a project's retrieval settings.
+// GET /projects/:id/settings
+func (h *ProjectHandler) GetSettings(c echo.Context) error {
+	settings, err := h.repo.GetSettings(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "project not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings replaces a project's settings. Keys left out of the body are
+// reset to their defaults. reindex_required is set when the change affects
+// how files are chunked; POST /projects/:id/reindex applies it. A new
+// fts_language is applied to existing chunks in the background.
+// PUT /projects/:id/settings
+func (h *ProjectHandler) UpdateSettings(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+
+	current, err := h.repo.GetSettings(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "project not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	settings := models.DefaultProjectSettings()
+	if err := json.NewDecoder(c.Request().Body).Decode(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid settings"})
+	}
+	settings.FTSLanguage = strings.ToLower(settings.FTSLanguage)
+	if msg := validateProjectSettings(settings); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	if err := h.repo.UpdateSettings(ctx, id, settings); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if settings.FTSLanguage != current.FTSLanguage {
+		h.ingestSvc.RebuildFTSAsync(id)
+	}
+	if settings.Summaries && !current.Summaries {
+		h.summarySvc.SummarizeAsync(id)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"settings":         settings,
+		"reindex_required": settings.NeedsReindex(current),
+	})
+}
+
+// ftsLanguages are the text search configurations shipped with PostgreSQL.
+var ftsLanguages = map[string]bool{
+	"simple": true, "arabic": true, "armenian": true, "basque": true, "catalan": true,
+	"danish": true, "dutch": true, "english": true, "finnish": true, "french": true,
+	"german": true, "greek": true, "hindi": true, "hungarian": true, "indonesian": true,
+	"irish": true, "italian": true, "lithuanian": true, "nepali": true, "norwegian": true,
+	"portuguese": true, "romanian": true, "russian": true, "serbian": true, "spanish": true,
+	"swedish": true, "tamil": true, "turkish": true, "yiddish": true,
+}
+
+// validateProjectSettings returns an error message for out-of-range settings, or "".
+func validateProjectSettings(s models.ProjectSettings) string {
+	switch {
+	case s.ChunkSize < 50 || s.ChunkSize > 8000:
+		return "chunk_size must be between 50 and 8000"
+	case s.ChunkOverlap < 0 || s.ChunkOverlap >= s.ChunkSize:
+		return "chunk_overlap must be at least 0 and less than chunk_size"
+	case s.Chunker != models.ChunkerAuto && s.Chunker != models.ChunkerCode && s.Chunker != models.ChunkerText:
+		return "chunker must be auto, code or text"
+	case !ftsLanguages[s.FTSLanguage]:
+		return "fts_language must be a built-in PostgreSQL text search configuration"
+	case s.TopK < 0 || s.TopK > 100:
+		return "top_k must be between 0 and 100"
+	case s.VectorWeight < 0 || s.FTSWeight < 0 || s.ExactWeight < 0 || s.SymbolWeight < 0:
+		return "vector_weight, fts_weight, exact_weight and symbol_weight must not be negative"
+	case s.VectorWeight == 0 && s.FTSWeight == 0:
+		return "vector_weight and fts_weight must not both be 0"
+	case s.RRFK < 1:
+		return "rrf_k must be at least 1"
+	case s.NeighborChunks < 0 || s.NeighborChunks > 5:
+		return "neighbor_chunks must be between 0 and 5"
+	case s.ImportNeighbors < 0 || s.ImportNeighbors > 10:
+		return "import_neighbors must be between 0 and 10"
+	case s.RefWindow < 0 || s.RefWindow > 200:
+		return "ref_window must be between 0 and 200"
+	case s.MMRLambda < 0 || s.MMRLambda > 1:
+		return "mmr_lambda must be between 0 and 1"
+	case s.MaxChunksPerFile < 0:
+		return "max_chunks_per_file must not be negative"
+	case s.MinSimilarity < -1 || s.MinSimilarity > 1:
+		return "min_similarity must be between -1 and 1"
+	case s.LowConfidenceSimilarity < -1 || s.LowConfidenceSimilarity > 1:
+		return "low_confidence_similarity must be between -1 and 1"
+	case s.MinScore < 0:
+		return "min_score must not be negative"
+	}
+	return ""
+}
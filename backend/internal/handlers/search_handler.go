@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+	"rag-chat-system/internal/services"
+)
+
+type SearchHandler struct {
+	ragSvc     *services.RAGService
+	grepSvc    *services.GrepService
+	symbolRepo *repositories.SymbolRepo
+}
+
+func NewSearchHandler(ragSvc *services.RAGService, grepSvc *services.GrepService, symbolRepo *repositories.SymbolRepo) *SearchHandler {
+	return &SearchHandler{ragSvc: ragSvc, grepSvc: grepSvc, symbolRepo: symbolRepo}
+}
+
+// Search runs retrieval exactly as a chat message would, including inline
+// path:/ext:/file: operators, and returns the trace with every chunk's
+// ranks and scores. Nothing is sent to the chat model unless the strategy
+// needs query expansions or the LLM reranker is configured.
+// POST /projects/search
+func (h *SearchHandler) Search(c echo.Context) error {
+	var req struct {
+		Query      string              `json:"query"`
+		ProjectIDs []string            `json:"project_ids"`
+		Filter     models.SearchFilter `json:"filter"`
+		Strategy   string              `json:"retrieval_strategy"`
+	}
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Query) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "query is required"})
+	}
+	if msg := validateSearchFilter(req.Filter); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	switch req.Strategy {
+	case "":
+		req.Strategy = models.RetrievalSingle
+	case models.RetrievalSingle, models.RetrievalMultiQuery, models.RetrievalHyDE:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "retrieval_strategy must be single, multi_query or hyde"})
+	}
+
+	query, inline := rag.ParseQueryFilters(req.Query)
+	trace, err := h.ragSvc.Retrieve(c.Request().Context(), query, req.ProjectIDs, req.Strategy, req.Filter.Merge(inline))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if trace.Chunks == nil {
+		trace.Chunks = []models.ChunkSearchResult{}
+	}
+	return c.JSON(http.StatusOK, trace)
+}
+
+// Grep finds lines of a project's files containing q, or matching it as a
+// regular expression with regex=true. ignore_case, path, ext and limit
+// (default 100, at most 1000) are optional; path and ext may repeat.
+// GET /projects/:id/grep
+func (h *SearchHandler) Grep(c echo.Context) error {
+	req := services.GrepRequest{
+		Pattern:    c.QueryParam("q"),
+		Regex:      c.QueryParam("regex") == "true",
+		IgnoreCase: c.QueryParam("ignore_case") == "true",
+		Limit:      100,
+	}
+	if req.Pattern == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "q is required"})
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 1000"})
+		}
+		req.Limit = n
+	}
+	params := c.QueryParams()
+	req.Filter = models.SearchFilter{Paths: params["path"], Exts: params["ext"]}
+	if msg := validateSearchFilter(req.Filter); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	result, err := h.grepSvc.Grep(c.Request().Context(), c.Param("id"), req)
+	if errors.Is(err, services.ErrInvalidPattern) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// Symbols looks up declarations by name: q matches any part of the name,
+// ignoring case, with exact and prefix matches first; "Type.method" also
+// matches the container. Without q the project's symbols are listed in
+// file order. kind and limit (default 50, at most 500) are optional.
+// GET /projects/:id/symbols
+func (h *SearchHandler) Symbols(c echo.Context) error {
+	limit := 50
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 500"})
+		}
+		limit = n
+	}
+
+	symbols, err := h.symbolRepo.Search(c.Request().Context(), c.Param("id"), strings.TrimSpace(c.QueryParam("q")), c.QueryParam("kind"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if symbols == nil {
+		symbols = []models.Symbol{}
+	}
+	return c.JSON(http.StatusOK, symbols)
+}
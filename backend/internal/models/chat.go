@@ -2,9 +2,21 @@ package models
 
 import "time"
 
+// Retrieval strategies selectable per chat.
+const (
+	RetrievalSingle     = "single"      // search with the question as asked
+	RetrievalMultiQuery = "multi_query" // search with paraphrases too and fuse the results
+	RetrievalHyDE       = "hyde"        // embed a hypothetical answer instead of the question
+)
+
 type Chat struct {
-	ID         string    `json:"id"`
-	Title      string    `json:"title"`
-	ProjectIDs []string  `json:"project_ids"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID                string    `json:"id"`
+	Title             string    `json:"title"`
+	ProjectIDs        []string  `json:"project_ids"`
+	Model             string    `json:"model"`
+	Temperature       *float32  `json:"temperature,omitempty"`
+	QueryRewrite      bool      `json:"query_rewrite"`
+	RetrievalStrategy string    `json:"retrieval_strategy"`
+	Verify            bool      `json:"verify"` // check answers against their context after streaming
+	CreatedAt         time.Time `json:"created_at"`
 }
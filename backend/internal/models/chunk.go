@@ -1,9 +1,132 @@
 package models
 
+// Chunk types. Summaries are generated by the chat model; a directory
+// summary belongs to the directory's files row.
+const (
+	ChunkTypeContent     = "content"
+	ChunkTypeFileSummary = "file_summary"
+	ChunkTypeDirSummary  = "dir_summary"
+)
+
 type DocumentChunk struct {
-	ID        string    `json:"id"`
-	ProjectID string    `json:"project_id"`
-	FileID    string    `json:"file_id"`
-	Content   string    `json:"content"`
-	Embedding []float32 `json:"-"`
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"project_id"`
+	FileID     string    `json:"file_id"`
+	FilePath   string    `json:"file_path"`
+	FileName   string    `json:"file_name"`
+	FileExt    string    `json:"file_ext"`
+	Content    string    `json:"content"`
+	SymbolName string    `json:"symbol_name,omitempty"`
+	SymbolKind string    `json:"symbol_kind,omitempty"`
+	StartLine  int       `json:"start_line"`
+	EndLine    int       `json:"end_line"`
+	Embedding  []float32 `json:"-"`
+
+	// Ordinal is the chunk's position among its file's chunks, from 0.
+	// StartByte and EndByte locate Content in the file (end exclusive).
+	Ordinal   int `json:"ordinal"`
+	StartByte int `json:"start_byte"`
+	EndByte   int `json:"end_byte"`
+
+	// EmbeddingModel is the model that produced Embedding.
+	EmbeddingModel string `json:"embedding_model"`
+	// ContentHash keys the embedding cache entry the vector came from.
+	ContentHash string `json:"-"`
+	// SearchText is what the full-text index is built from; see rag.SearchText.
+	SearchText string `json:"-"`
+
+	ChunkType string `json:"chunk_type"`
+	// SourceHash identifies the text a summary was generated from.
+	SourceHash string `json:"-"`
+}
+
+// ChunkSearchResult is a single chunk returned by hybrid retrieval, together
+// with the file it came from and the ranks that produced its fused score.
+// VectorDistance is the cosine distance behind Similarity (1 - Similarity).
+// SymbolRank is set on chunks defining a symbol named in the query.
+// LinkedFrom is set on chunks added because their file imports, or is
+// imported by, the hit file at that path.
+// ReferencedLines is set on file excerpts read for file:line references in
+// the question and lists the lines referenced.
+// Ordinal is nil for chunks indexed before ordinals were recorded. When
+// neighbor expansion merged surrounding chunks into Content, NeighborIDs
+// lists them.
+type ChunkSearchResult struct {
+	ChunkID         string   `json:"chunk_id"`
+	ChunkType       string   `json:"chunk_type"`
+	ProjectID       string   `json:"project_id"`
+	FileID          string   `json:"file_id"`
+	FilePath        string   `json:"file_path"`
+	FileName        string   `json:"file_name"`
+	Content         string   `json:"content"`
+	SymbolName      string   `json:"symbol_name,omitempty"`
+	SymbolKind      string   `json:"symbol_kind,omitempty"`
+	StartLine       int      `json:"start_line"`
+	EndLine         int      `json:"end_line"`
+	Ordinal         *int     `json:"ordinal,omitempty"`
+	StartByte       int      `json:"start_byte"`
+	EndByte         int      `json:"end_byte"`
+	NeighborIDs     []string `json:"neighbor_ids,omitempty"`
+	VectorRank      *int     `json:"vector_rank,omitempty"`
+	FTSRank         *int     `json:"fts_rank,omitempty"`
+	ExactRank       *int     `json:"exact_rank,omitempty"`
+	SymbolRank      *int     `json:"symbol_rank,omitempty"`
+	Similarity      *float64 `json:"similarity,omitempty"`
+	VectorDistance  *float64 `json:"vector_distance,omitempty"`
+	Score           float64  `json:"score"`
+	RerankScore     *float64 `json:"rerank_score,omitempty"`
+	LinkedFrom      string   `json:"linked_from,omitempty"`
+	ReferencedLines []int    `json:"referenced_lines,omitempty"`
+}
+
+// SearchFilter narrows retrieval by file. Paths are globs over the
+// project-relative path ("backend/internal/**", "*.go"); a pattern without
+// wildcards matches that file or directory at any depth. Exts are
+// extensions with or without the dot. A chunk must match one entry of every
+// non-empty include list and no entry of the exclude lists.
+type SearchFilter struct {
+	Paths        []string `json:"paths,omitempty"`
+	Exts         []string `json:"exts,omitempty"`
+	FileIDs      []string `json:"file_ids,omitempty"`
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+	ExcludeExts  []string `json:"exclude_exts,omitempty"`
+}
+
+// Empty reports whether the filter matches everything.
+func (f SearchFilter) Empty() bool {
+	return len(f.Paths) == 0 && len(f.Exts) == 0 && len(f.FileIDs) == 0 &&
+		len(f.ExcludePaths) == 0 && len(f.ExcludeExts) == 0
+}
+
+// Merge returns a filter with the entries of both f and other.
+func (f SearchFilter) Merge(other SearchFilter) SearchFilter {
+	return SearchFilter{
+		Paths:        append(append([]string{}, f.Paths...), other.Paths...),
+		Exts:         append(append([]string{}, f.Exts...), other.Exts...),
+		FileIDs:      append(append([]string{}, f.FileIDs...), other.FileIDs...),
+		ExcludePaths: append(append([]string{}, f.ExcludePaths...), other.ExcludePaths...),
+		ExcludeExts:  append(append([]string{}, f.ExcludeExts...), other.ExcludeExts...),
+	}
+}
+
+// RetrievalTrace records how the context for a query was retrieved.
+// Expansions are the paraphrases (multi_query) or hypothetical answer (hyde)
+// searched alongside or instead of Query. Filtered counts candidates dropped
+// by the project relevance thresholds; LowConfidence is set when none of the
+// kept chunks is a close match. References counts the excerpts read for
+// file:line references in Query, which lead Chunks.
+type RetrievalTrace struct {
+	Query         string              `json:"query"`
+	Filter        *SearchFilter       `json:"filter,omitempty"`
+	Strategy      string              `json:"strategy"`
+	Expansions    []string            `json:"expansions,omitempty"`
+	StrategyErr   string              `json:"strategy_error,omitempty"`
+	Candidates    int                 `json:"candidates"`
+	Filtered      int                 `json:"filtered"`
+	Reranker      string              `json:"reranker,omitempty"`
+	RerankErr     string              `json:"rerank_error,omitempty"`
+	LowConfidence bool                `json:"low_confidence"`
+	References    int                 `json:"references,omitempty"`
+	ReferenceErr  string              `json:"reference_error,omitempty"`
+	Chunks        []ChunkSearchResult `json:"chunks"`
 }
@@ -0,0 +1,35 @@
+package models
+
+// FileImport is one import statement of an indexed file, as parsed by
+// rag.ParseImports; go.mod files record their module path the same way.
+type FileImport struct {
+	FileID   string `json:"file_id"`
+	FilePath string `json:"file_path"`
+	Kind     string `json:"kind"`
+	Target   string `json:"target"`
+	Line     int    `json:"line"`
+}
+
+// DependencyGraph is the file-level import graph of a project. Edges point
+// from the importing file to the imported one; Unresolved counts imports
+// of files outside the project, such as standard library or third-party
+// packages.
+type DependencyGraph struct {
+	Nodes      []GraphNode `json:"nodes"`
+	Edges      []GraphEdge `json:"edges"`
+	Unresolved int         `json:"unresolved"`
+}
+
+type GraphNode struct {
+	FileID     string `json:"file_id"`
+	Path       string `json:"path"`
+	Imports    int    `json:"imports"`
+	ImportedBy int    `json:"imported_by"`
+}
+
+// GraphEdge links two files by ID; Line is the import's line in From.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Line int    `json:"line"`
+}
@@ -3,9 +3,106 @@ package models
 import "time"
 
 type Message struct {
-	ID        string    `json:"id"`
-	ChatID    string    `json:"chat_id"`
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             string     `json:"id"`
+	ChatID         string     `json:"chat_id"`
+	Role           string     `json:"role"`
+	Content        string     `json:"content"`
+	Citations      []Citation `json:"citations,omitempty"`
+	RewrittenQuery string     `json:"rewritten_query,omitempty"`
+	// Verification is set on assistant messages of chats with verify on.
+	Verification *Verification `json:"verification,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// Verification is the result of checking an assistant answer after it was
+// streamed. Claims are the answer's statements as judged against the context
+// entries it was given; Unsupported counts those without support.
+// UnknownFiles and UnknownIdentifiers are names the answer mentions that the
+// project has no file, symbol or text for. Error is set when the claim check
+// failed; the name checks still ran.
+type Verification struct {
+	Claims             []ClaimCheck `json:"claims"`
+	Unsupported        int          `json:"unsupported"`
+	UnknownFiles       []string     `json:"unknown_files"`
+	UnknownIdentifiers []string     `json:"unknown_identifiers"`
+	Error              string       `json:"error,omitempty"`
+}
+
+// ClaimCheck is one statement of an answer and whether the context entries
+// with the given markers support it.
+type ClaimCheck struct {
+	Claim     string `json:"claim"`
+	Supported bool   `json:"supported"`
+	Markers   []int  `json:"markers,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// MessageTrace records how an assistant message was produced, so a wrong
+// answer can be traced back to what the model was actually sent. Chunks are
+// those retrieved, best first; InPrompt marks the ones that fit the budget.
+// References counts the excerpts read for file:line references in the
+// question. StrategyErr, RerankErr and ReferenceErr record the retrieval
+// steps that failed and were skipped. RepoMap is set when the project map
+// was added to the system prompt.
+type MessageTrace struct {
+	MessageID      string        `json:"message_id"`
+	Model          string        `json:"model"`
+	Temperature    *float32      `json:"temperature,omitempty"`
+	SearchQuery    string        `json:"search_query"`
+	RewrittenQuery string        `json:"rewritten_query,omitempty"`
+	Filter         *SearchFilter `json:"filter,omitempty"`
+	Strategy       string        `json:"strategy,omitempty"`
+	Expansions     []string      `json:"expansions,omitempty"`
+	StrategyErr    string        `json:"strategy_error,omitempty"`
+	Reranker       string        `json:"reranker,omitempty"`
+	RerankErr      string        `json:"rerank_error,omitempty"`
+	RetrievalErr   string        `json:"retrieval_error,omitempty"`
+	Candidates     int           `json:"candidates"`
+	Filtered       int           `json:"filtered"`
+	LowConfidence  bool          `json:"low_confidence"`
+	References     int           `json:"references,omitempty"`
+	ReferenceErr   string        `json:"reference_error,omitempty"`
+	RepoMap        bool          `json:"repo_map,omitempty"`
+	Chunks         []TracedChunk `json:"chunks"`
+	SystemPrompt   string        `json:"system_prompt"`
+	HistoryIDs     []string      `json:"history_message_ids"`
+	Usage          TokenUsage    `json:"usage"`
+	AnswerTokens   int           `json:"answer_tokens"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// TracedChunk is a retrieved chunk as recorded in a MessageTrace.
+type TracedChunk struct {
+	ChunkID         string   `json:"chunk_id"`
+	FilePath        string   `json:"file_path"`
+	Score           float64  `json:"score"`
+	Similarity      *float64 `json:"similarity,omitempty"`
+	RerankScore     *float64 `json:"rerank_score,omitempty"`
+	VectorRank      *int     `json:"vector_rank,omitempty"`
+	FTSRank         *int     `json:"fts_rank,omitempty"`
+	ExactRank       *int     `json:"exact_rank,omitempty"`
+	SymbolRank      *int     `json:"symbol_rank,omitempty"`
+	LinkedFrom      string   `json:"linked_from,omitempty"`
+	ReferencedLines []int    `json:"referenced_lines,omitempty"`
+	InPrompt        bool     `json:"in_prompt"`
+}
+
+// TokenUsage reports how many tokens each part of an assembled prompt used.
+type TokenUsage struct {
+	Budget   int `json:"budget"`
+	System   int `json:"system"`
+	Context  int `json:"context"`
+	History  int `json:"history"`
+	Question int `json:"question"`
+	Total    int `json:"total"`
+}
+
+// Citation maps an inline marker such as [1] in an assistant answer to the
+// source file the referenced context chunk was taken from.
+type Citation struct {
+	Marker   int    `json:"marker"`
+	ChunkID  string `json:"chunk_id"`
+	FileID   string `json:"file_id"`
+	FilePath string `json:"file_path"`
+	FileName string `json:"file_name"`
 }
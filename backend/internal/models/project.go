@@ -10,4 +10,99 @@ type Project struct {
 	GitTokenEncrypted *string    `json:"-"`
 	LastSyncedAt      *time.Time `json:"last_synced_at,omitempty"`
 	CreatedAt         time.Time  `json:"created_at"`
+
+	Settings ProjectSettings `json:"settings"`
+}
+
+// Chunker types selectable per project.
+const (
+	ChunkerAuto = "auto" // split on symbols where the language is supported, else as text
+	ChunkerCode = "code" // always try symbol-aware splitting
+	ChunkerText = "text" // always split as plain text
+)
+
+// ProjectSettings tune indexing and retrieval for a single project. They are
+// stored as JSON; keys missing from the stored document keep their defaults.
+type ProjectSettings struct {
+	// Indexing. Changing any of these only affects files indexed afterwards,
+	// so existing chunks must be reindexed.
+	ChunkSize    int    `json:"chunk_size"`    // tokens per chunk
+	ChunkOverlap int    `json:"chunk_overlap"` // tokens shared by consecutive chunks
+	Chunker      string `json:"chunker"`
+	// FTSLanguage is the PostgreSQL text search configuration. "simple"
+	// skips stemming and stop words, which suits code and languages
+	// PostgreSQL has no dictionary for. Existing chunks are backfilled in the
+	// background when it changes; no reindex is needed.
+	FTSLanguage string `json:"fts_language"`
+	// Summaries has the chat model summarize every file and directory after
+	// uploads, syncs and reindexes. Summaries are embedded as chunks of
+	// their own so broad questions can match them, and are cached by input
+	// so unchanged files are not summarized again. Off by default, since it
+	// costs a chat model call per file and directory.
+	Summaries bool `json:"summaries"`
+
+	// Retrieval
+	TopK         int     `json:"top_k"` // chunks given to the model; 0 uses the server default
+	VectorWeight float64 `json:"vector_weight"`
+	FTSWeight    float64 `json:"fts_weight"`
+	ExactWeight  float64 `json:"exact_weight"`  // weight of verbatim identifier and quoted-text matches
+	SymbolWeight float64 `json:"symbol_weight"` // weight of chunks defining symbols named in the query
+	RRFK         int     `json:"rrf_k"`         // Reciprocal Rank Fusion constant
+	// NeighborChunks widens each hit with this many chunks before and after
+	// it from the same file; overlapping windows are merged.
+	NeighborChunks int `json:"neighbor_chunks"`
+	// ImportNeighbors adds the best-matching chunk of up to this many files
+	// that the top hits import or are imported by, giving questions about
+	// how code fits together the other side of each dependency. 0 disables
+	// it.
+	ImportNeighbors int `json:"import_neighbors"`
+	// RefWindow is how many lines before and after each file:line
+	// reference or stack frame pasted into a question are read from the
+	// referenced file and put ahead of the retrieved chunks. 0 disables it.
+	RefWindow int `json:"ref_window"`
+	// MMRLambda trades relevance (1) against diversity (0) when choosing
+	// the top_k chunks, so near-duplicate overlapping chunks do not crowd
+	// out other sources. 1, the default, keeps the ranked order; lower
+	// values also widen the candidate pool and fetch candidate embeddings.
+	MMRLambda float64 `json:"mmr_lambda"`
+	// MaxChunksPerFile caps the chunks taken from one file; 0 means no cap.
+	MaxChunksPerFile int `json:"max_chunks_per_file"`
+
+	// MinSimilarity drops chunks whose cosine similarity to the query is
+	// below it, unless full-text, exact or symbol search also matched them.
+	MinSimilarity float64 `json:"min_similarity"`
+	// MinScore drops chunks whose fused RRF score is below it.
+	MinScore float64 `json:"min_score"`
+	// LowConfidenceSimilarity marks retrieval as low confidence when no kept
+	// chunk reaches it; the model is then told the project may not cover
+	// the question.
+	LowConfidenceSimilarity float64 `json:"low_confidence_similarity"`
+}
+
+// DefaultProjectSettings returns the settings used for projects that have
+// not been configured.
+func DefaultProjectSettings() ProjectSettings {
+	return ProjectSettings{
+		ChunkSize:               500,
+		ChunkOverlap:            100,
+		Chunker:                 ChunkerAuto,
+		FTSLanguage:             "english",
+		VectorWeight:            1,
+		FTSWeight:               1,
+		ExactWeight:             1,
+		SymbolWeight:            1,
+		RRFK:                    60,
+		RefWindow:               20,
+		MMRLambda:               1,
+		MinSimilarity:           0.2,
+		LowConfidenceSimilarity: 0.35,
+	}
+}
+
+// NeedsReindex reports whether switching from old to s changes how files are
+// chunked.
+func (s ProjectSettings) NeedsReindex(old ProjectSettings) bool {
+	return s.ChunkSize != old.ChunkSize ||
+		s.ChunkOverlap != old.ChunkOverlap ||
+		s.Chunker != old.Chunker
 }
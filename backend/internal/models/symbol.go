@@ -0,0 +1,16 @@
+package models
+
+// Symbol is a declaration in an indexed file. Lines are 1-based and
+// inclusive; Container is the type or class a method belongs to.
+type Symbol struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	FileID    string `json:"file_id"`
+	FilePath  string `json:"file_path"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Container string `json:"container,omitempty"`
+	Signature string `json:"signature"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
@@ -0,0 +1,72 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxAnswerRefs bounds the file names and identifiers checked per answer.
+const maxAnswerRefs = 30
+
+// sourceExts are the extensions that make a bare name a file name.
+const sourceExts = `go|py|js|jsx|mjs|ts|tsx|rs|rb|java|kt|c|h|cpp|hpp|cs|php|swift|sql|proto|vue|svelte|ya?ml|toml|json|md|mod|sh`
+
+var (
+	fencedBlock = regexp.MustCompile("(?s)```.*?(```|$)")
+	inlineCode  = regexp.MustCompile("`([^`\n]+)`")
+	// codePath is an inline code span naming a file, optionally with a line.
+	codePath  = regexp.MustCompile(`^(?:\.{1,2}/)?((?:[\w.@\-]+/)*[\w\-][\w.\-]*\.[A-Za-z]\w{0,9})(:\d+(?:-\d+)?)?$`)
+	sourceExt = regexp.MustCompile(`\.(?:` + sourceExts + `)$`)
+	// codeIdent is an inline code span naming an identifier, possibly
+	// qualified ("ChunkRepo.HybridSearch") or called ("Retrieve()").
+	codeIdent = regexp.MustCompile(`^([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)(?:\(\))?$`)
+	// prosePath is a file path in running text. Only source extensions
+	// count, so abbreviations like "e.g." are not taken for files.
+	prosePath = regexp.MustCompile(`(?:^|[\s(])((?:[\w.\-]+/)*[\w\-]+\.(?:` + sourceExts + `))\b`)
+	// proseCall is a function call in running text.
+	proseCall = regexp.MustCompile(`\b([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\(\)`)
+)
+
+// AnswerReferences returns the file paths and identifiers an answer names:
+// inline code spans that look like paths or identifiers, plus paths with a
+// source extension and calls such as "Retrieve()" in the text. Fenced code
+// blocks are skipped, since examples may introduce names of their own.
+// Identifiers shorter than three characters are ignored.
+func AnswerReferences(answer string) (files, identifiers []string) {
+	text := fencedBlock.ReplaceAllString(answer, " ")
+	seen := make(map[string]bool)
+	addFile := func(p string) {
+		p = strings.TrimPrefix(p, "./")
+		if !seen["f:"+p] && len(files) < maxAnswerRefs {
+			seen["f:"+p] = true
+			files = append(files, p)
+		}
+	}
+	addIdent := func(name string) {
+		if len(name) >= 3 && !seen["i:"+name] && len(identifiers) < maxAnswerRefs {
+			seen["i:"+name] = true
+			identifiers = append(identifiers, name)
+		}
+	}
+
+	for _, m := range inlineCode.FindAllStringSubmatch(text, -1) {
+		span := strings.TrimSpace(m[1])
+		// "a/b.go", "main.go" and "x.ts:12" are files; "pkg.Func" is not
+		if p := codePath.FindStringSubmatch(span); p != nil &&
+			(strings.Contains(p[1], "/") || p[2] != "" || sourceExt.MatchString(p[1])) {
+			addFile(p[1])
+			continue
+		}
+		if id := codeIdent.FindStringSubmatch(span); id != nil {
+			addIdent(id[1])
+		}
+	}
+	prose := inlineCode.ReplaceAllString(text, " ")
+	for _, m := range prosePath.FindAllStringSubmatch(prose, -1) {
+		addFile(m[1])
+	}
+	for _, m := range proseCall.FindAllStringSubmatch(prose, -1) {
+		addIdent(m[1])
+	}
+	return files, identifiers
+}
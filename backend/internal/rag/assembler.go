@@ -0,0 +1,135 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+
+	"rag-chat-system/internal/models"
+)
+
+// ContextSeparator separates context entries in the system prompt.
+const ContextSeparator = "\n\n---\n\n"
+
+// messageOverhead approximates the tokens the chat format adds per message.
+const messageOverhead = 4
+
+// minTruncatedTokens is the smallest remainder worth filling with a truncated
+// chunk or message; anything less is dropped instead.
+const minTruncatedTokens = 64
+
+// PromptBudget splits a token budget between the parts of a chat prompt.
+// The system prompt and the question are always included; of what remains,
+// ContextShare goes to retrieved chunks and the rest (plus anything the
+// chunks leave unused) to chat history.
+type PromptBudget struct {
+	Total        int
+	ContextShare float64
+}
+
+// PromptParts are the inputs to AssemblePrompt.
+type PromptParts struct {
+	Template string           // system prompt with a %s verb for the context
+	Fallback string           // system prompt used when no context entry fits
+	Context  []string         // formatted context entries, best first
+	History  []models.Message // oldest first
+	Question string
+}
+
+// Prompt is an assembled chat prompt. ContextUsed is the number of leading
+// context entries included; the last one may have been truncated.
+type Prompt struct {
+	System      string
+	History     []models.Message
+	ContextUsed int
+	Usage       models.TokenUsage
+}
+
+// AssemblePrompt fits the system prompt, retrieved context and chat history
+// into budget, preferring higher-ranked chunks and more recent messages.
+func AssemblePrompt(parts PromptParts, budget PromptBudget) Prompt {
+	usage := models.TokenUsage{
+		Budget:   budget.Total,
+		System:   TokenCount(strings.Replace(parts.Template, "%s", "", 1)) + messageOverhead,
+		Question: TokenCount(parts.Question) + messageOverhead,
+	}
+	remaining := max(budget.Total-usage.System-usage.Question, 0)
+
+	// Context entries, best first, up to their share of the budget
+	contextBudget := int(float64(remaining) * budget.ContextShare)
+	sepTokens := TokenCount(ContextSeparator)
+	var entries []string
+	for _, entry := range parts.Context {
+		cost := TokenCount(entry)
+		if len(entries) > 0 {
+			cost += sepTokens
+		}
+		if usage.Context+cost <= contextBudget {
+			entries = append(entries, entry)
+			usage.Context += cost
+			continue
+		}
+		if left := contextBudget - usage.Context - sepTokens; left >= minTruncatedTokens {
+			entries = append(entries, TruncateTokens(entry, left))
+			usage.Context += sepTokens + TokenCount(entries[len(entries)-1])
+		}
+		break
+	}
+
+	system := parts.Fallback
+	if len(entries) > 0 {
+		system = fmt.Sprintf(parts.Template, strings.Join(entries, ContextSeparator))
+	} else {
+		usage.System = TokenCount(parts.Fallback) + messageOverhead
+	}
+
+	// History, most recent first, with whatever is left
+	historyBudget := max(budget.Total-usage.System-usage.Question-usage.Context, 0)
+	var history []models.Message
+	for i := len(parts.History) - 1; i >= 0; i-- {
+		msg := parts.History[i]
+		cost := TokenCount(msg.Content) + messageOverhead
+		if usage.History+cost > historyBudget {
+			if left := historyBudget - usage.History - messageOverhead; left >= minTruncatedTokens {
+				msg.Content = TruncateTokens(msg.Content, left)
+				history = append(history, msg)
+				usage.History += TokenCount(msg.Content) + messageOverhead
+			}
+			break
+		}
+		history = append(history, msg)
+		usage.History += cost
+	}
+	// Restore chronological order
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	usage.Total = usage.System + usage.Context + usage.History + usage.Question
+	return Prompt{
+		System:      system,
+		History:     history,
+		ContextUsed: len(entries),
+		Usage:       usage,
+	}
+}
+
+// TokenCount returns the number of cl100k_base tokens in text.
+func TokenCount(text string) int {
+	return tokenLen(text)
+}
+
+// TruncateTokens shortens text to at most n tokens, cutting back to a line
+// boundary when one is reasonably close and marking the cut.
+func TruncateTokens(text string, n int) string {
+	const marker = "\n[...truncated]"
+	tokens := tokenEncoder().Encode(text, nil, nil)
+	if len(tokens) <= n {
+		return text
+	}
+	keep := max(n-tokenLen(marker), 0)
+	cut := tokenEncoder().Decode(tokens[:keep])
+	if i := strings.LastIndexByte(cut, '\n'); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return cut + marker
+}
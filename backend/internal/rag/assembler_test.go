@@ -0,0 +1,82 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"rag-chat-system/internal/models"
+)
+
+func TestAssemblePromptBudget(t *testing.T) {
+	requireTokenizer(t)
+
+	const (
+		template = "Answer from the context below.\n%s"
+		fallback = "No context was found."
+		question = "How do sessions expire?"
+	)
+	// Entries and messages of about 100 tokens each
+	text := func(word string) string { return strings.TrimSpace(strings.Repeat(word+" ", 100)) }
+	context := []string{text("alpha"), text("bravo"), text("charlie")}
+	history := []models.Message{
+		{ID: "m1", Role: "user", Content: text("delta")},
+		{ID: "m2", Role: "assistant", Content: text("echo")},
+		{ID: "m3", Role: "user", Content: text("foxtrot")},
+	}
+	fixed := TokenCount(strings.Replace(template, "%s", "", 1)) + TokenCount(question) + 2*messageOverhead
+	fixedFallback := TokenCount(fallback) + TokenCount(question) + 2*messageOverhead
+
+	tests := []struct {
+		name        string
+		context     []string
+		history     []models.Message
+		total       int
+		share       float64
+		contextUsed int
+		truncated   bool // the last context entry or oldest message was cut
+		fallback    bool
+		historyIDs  []string
+	}{
+		{name: "everything fits", context: context, history: history, total: fixed + 1000, share: 0.5, contextUsed: 3, historyIDs: []string{"m1", "m2", "m3"}},
+		{name: "context share caps chunks", context: context, total: fixed + 500, share: 0.5, contextUsed: 2},
+		{name: "crossing chunk truncated", context: context, total: fixed + 600, share: 0.5, contextUsed: 3, truncated: true},
+		{name: "history gets unused context budget", context: context[:1], history: history, total: fixed + 500, share: 0.8, contextUsed: 1, historyIDs: []string{"m1", "m2", "m3"}},
+		{name: "nothing fits", context: context, history: history, total: fixed + 50, share: 0.5, fallback: true},
+		{name: "recent history first", history: history, total: fixedFallback + 230, share: 0.5, fallback: true, historyIDs: []string{"m2", "m3"}},
+		{name: "oldest message truncated", history: history, total: fixedFallback + 300, share: 0.5, fallback: true, truncated: true, historyIDs: []string{"m1", "m2", "m3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := AssemblePrompt(PromptParts{Template: template, Fallback: fallback, Context: tt.context, History: tt.history, Question: question},
+				PromptBudget{Total: tt.total, ContextShare: tt.share})
+
+			if p.ContextUsed != tt.contextUsed {
+				t.Errorf("context used = %d, want %d", p.ContextUsed, tt.contextUsed)
+			}
+			if got := p.System == fallback; got != tt.fallback {
+				t.Errorf("fallback system prompt = %v, want %v", got, tt.fallback)
+			}
+			var ids []string
+			for _, m := range p.History {
+				ids = append(ids, m.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.historyIDs) {
+				t.Errorf("history = %v, want %v", ids, tt.historyIDs)
+			}
+			truncated := strings.Contains(p.System, "[...truncated]") ||
+				(len(p.History) > 0 && strings.Contains(p.History[0].Content, "[...truncated]"))
+			if truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.truncated)
+			}
+
+			u := p.Usage
+			if u.Total > tt.total {
+				t.Errorf("used %d tokens of a %d budget", u.Total, tt.total)
+			}
+			if u.Total != u.System+u.Context+u.History+u.Question || u.Budget != tt.total {
+				t.Errorf("usage %+v does not add up", u)
+			}
+		})
+	}
+}
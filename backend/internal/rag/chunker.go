@@ -3,33 +3,43 @@ package rag
 import (
 	"log"
 	"strings"
+	"sync"
 
 	tiktoken "github.com/pkoukk/tiktoken-go"
 )
 
 var defaultSeparators = []string{"\n\n", "\n", ". ", " "}
 
-// tokenEncoder is initialized once for cl100k_base (used by text-embedding-3-small).
-var tokenEncoder *tiktoken.Tiktoken
+var (
+	tokenEncoderOnce sync.Once
+	tokenEncoderVal  *tiktoken.Tiktoken
+)
 
-func init() {
-	enc, err := tiktoken.GetEncoding("cl100k_base")
-	if err != nil {
-		log.Fatalf("failed to load tiktoken encoding: %v", err)
-	}
-	tokenEncoder = enc
+// tokenEncoder returns the cl100k_base encoding (used by
+// text-embedding-3-small), loading it on first use. Loading may download the
+// encoding unless TIKTOKEN_CACHE_DIR holds a copy, so it is not done at init
+// and code that never counts tokens runs offline.
+func tokenEncoder() *tiktoken.Tiktoken {
+	tokenEncoderOnce.Do(func() {
+		enc, err := tiktoken.GetEncoding("cl100k_base")
+		if err != nil {
+			log.Fatalf("failed to load tiktoken encoding: %v", err)
+		}
+		tokenEncoderVal = enc
+	})
+	return tokenEncoderVal
 }
 
 // tokenLen returns the number of tokens in the text using cl100k_base encoding.
 func tokenLen(text string) int {
-	return len(tokenEncoder.Encode(text, nil, nil))
+	return len(tokenEncoder().Encode(text, nil, nil))
 }
 
 func ChunkText(text string, chunkSize, overlap int) []string {
 	if chunkSize <= 0 {
 		chunkSize = 500
 	}
-	if overlap <= 0 {
+	if overlap < 0 {
 		overlap = 100
 	}
 
@@ -94,7 +104,7 @@ func recursiveSplit(text string, chunkSize, overlap int, separators []string) []
 }
 
 func splitByTokens(text string, chunkSize, overlap int) []string {
-	tokens := tokenEncoder.Encode(text, nil, nil)
+	tokens := tokenEncoder().Encode(text, nil, nil)
 	length := len(tokens)
 	if length == 0 {
 		return nil
@@ -104,7 +114,7 @@ func splitByTokens(text string, chunkSize, overlap int) []string {
 	start := 0
 	for start < length {
 		end := min(start+chunkSize, length)
-		chunk := tokenEncoder.Decode(tokens[start:end])
+		chunk := tokenEncoder().Decode(tokens[start:end])
 		if strings.TrimSpace(chunk) != "" {
 			chunks = append(chunks, chunk)
 		}
@@ -114,9 +124,9 @@ func splitByTokens(text string, chunkSize, overlap int) []string {
 }
 
 func getOverlapSuffix(text string, overlap int) string {
-	tokens := tokenEncoder.Encode(text, nil, nil)
+	tokens := tokenEncoder().Encode(text, nil, nil)
 	if len(tokens) <= overlap {
 		return text
 	}
-	return tokenEncoder.Decode(tokens[len(tokens)-overlap:])
+	return tokenEncoder().Decode(tokens[len(tokens)-overlap:])
 }
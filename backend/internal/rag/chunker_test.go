@@ -0,0 +1,107 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+
+	tiktoken "github.com/pkoukk/tiktoken-go"
+)
+
+// requireTokenizer skips tests that count tokens when the cl100k_base
+// encoding can be neither downloaded nor read from TIKTOKEN_CACHE_DIR.
+func requireTokenizer(t *testing.T) {
+	t.Helper()
+	if _, err := tiktoken.GetEncoding("cl100k_base"); err != nil {
+		t.Skipf("tokenizer unavailable: %v", err)
+	}
+}
+
+const paragraphs = `The ingest service splits every uploaded file into chunks before embedding them.
+
+Chunks that are too large are split again on lines, then sentences, then words.
+
+Consecutive chunks may share a few tokens so a sentence cut in two still appears whole in one of them.
+
+The overlap is configured per project and may be turned off entirely.`
+
+func TestChunkTextOverlap(t *testing.T) {
+	requireTokenizer(t)
+
+	tests := []struct {
+		name    string
+		overlap int
+		// shared reports whether consecutive chunks repeat text
+		shared bool
+	}{
+		{name: "zero overlap is honored", overlap: 0, shared: false},
+		{name: "positive overlap repeats text", overlap: 5, shared: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkText(paragraphs, 25, tt.overlap)
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want several", len(chunks))
+			}
+			joined := strings.Join(chunks, "")
+			if got := joined != paragraphs; got != tt.shared {
+				t.Errorf("chunks joined differ from the text = %v, want %v\nchunks: %q", got, tt.shared, chunks)
+			}
+		})
+	}
+}
+
+const goSource = `package demo
+
+import "fmt"
+
+// Hello greets the world.
+func Hello() {
+	fmt.Println("hello")
+}
+
+type Greeter struct {
+	Name string
+}
+
+func (g Greeter) Greet() string {
+	return "hi " + g.Name
+}
+`
+
+func TestChunkCodeLineRanges(t *testing.T) {
+	requireTokenizer(t)
+
+	for _, overlap := range []int{0, 20} {
+		chunks := ChunkCode(goSource, ".go", 200, overlap)
+		lines := strings.Split(goSource, "\n")
+		symbols := map[string]Chunk{}
+		for _, c := range chunks {
+			if c.EndByte == 0 {
+				t.Errorf("overlap %d: chunk %q has no byte range", overlap, c.Content)
+				continue
+			}
+			if got := goSource[c.StartByte:c.EndByte]; got != c.Content {
+				t.Errorf("overlap %d: bytes %d-%d hold %q, want %q", overlap, c.StartByte, c.EndByte, got, c.Content)
+			}
+			first := strings.TrimRight(strings.SplitN(c.Content, "\n", 2)[0], "\n")
+			if lines[c.StartLine-1] != first {
+				t.Errorf("overlap %d: line %d is %q, want the chunk's first line %q", overlap, c.StartLine, lines[c.StartLine-1], first)
+			}
+			if c.SymbolName != "" {
+				symbols[c.SymbolName] = c
+			}
+		}
+
+		want := map[string][2]int{"Hello": {5, 8}, "Greeter": {10, 12}, "Greeter.Greet": {14, 16}}
+		for name, r := range want {
+			c, ok := symbols[name]
+			if !ok {
+				t.Errorf("overlap %d: no chunk for %s; got %v", overlap, name, symbols)
+				continue
+			}
+			if c.StartLine != r[0] || c.EndLine != r[1] {
+				t.Errorf("overlap %d: %s spans lines %d-%d, want %d-%d", overlap, name, c.StartLine, c.EndLine, r[0], r[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package rag
+
+import (
+	"sort"
+	"strings"
+)
+
+// Chunk is a piece of a file produced by a chunker. Chunks cut along a code
+// symbol carry its name and kind; line numbers are 1-based and inclusive.
+// StartByte and EndByte give Content's position in the file (end exclusive);
+// consecutive chunks overlap by the chunker's overlap.
+type Chunk struct {
+	Content    string
+	SymbolName string
+	SymbolKind string
+	StartLine  int
+	EndLine    int
+	StartByte  int
+	EndByte    int
+}
+
+// Symbol is a declaration found in a source file. Extract reports top-level
+// declarations only, with Go methods named "Recv.Name"; IndexSymbols also
+// reports members, named plainly with Container set to the enclosing type.
+type Symbol struct {
+	Name      string
+	Kind      string // "function", "method", "type", "class", ...
+	StartLine int
+	EndLine   int
+	Container string // type or class a method or member belongs to
+	Signature string // declaration header, e.g. "func (r *Repo) Get(id string) error"
+}
+
+// SymbolExtractor finds the top-level declarations of a source file.
+type SymbolExtractor interface {
+	Extract(content string) ([]Symbol, error)
+}
+
+// symbolExtractors maps lower-case file extensions to their extractor.
+var symbolExtractors = map[string]SymbolExtractor{
+	".go":   goExtractor{},
+	".js":   jsExtractor,
+	".jsx":  jsExtractor,
+	".mjs":  jsExtractor,
+	".cjs":  jsExtractor,
+	".ts":   jsExtractor,
+	".tsx":  jsExtractor,
+	".py":   pythonExtractor,
+	".rs":   rustExtractor,
+	".java": javaExtractor,
+	".kt":   javaExtractor,
+	".cs":   javaExtractor,
+}
+
+// RegisterSymbolExtractor adds or replaces the extractor used for ext.
+func RegisterSymbolExtractor(ext string, e SymbolExtractor) {
+	symbolExtractors[strings.ToLower(ext)] = e
+}
+
+// HasSymbolExtractor reports whether files with ext can be chunked by symbol.
+func HasSymbolExtractor(ext string) bool {
+	_, ok := symbolExtractors[strings.ToLower(ext)]
+	return ok
+}
+
+// ChunkFile picks the chunker for a file extension: symbol-aware chunking for
+// known source languages, plain recursive text splitting for everything else.
+func ChunkFile(content, ext string, chunkSize, overlap int) []Chunk {
+	if HasSymbolExtractor(ext) {
+		return ChunkCode(content, ext, chunkSize, overlap)
+	}
+	return ChunkTextLines(content, chunkSize, overlap)
+}
+
+// ChunkTextLines is ChunkText with each chunk's line range attached.
+func ChunkTextLines(text string, chunkSize, overlap int) []Chunk {
+	return locateChunks(text, ChunkText(text, chunkSize, overlap), "", "")
+}
+
+// ChunkCode splits source code on function, method and type boundaries.
+// Each declaration becomes its own chunk (split further only if it exceeds
+// chunkSize tokens); code between declarations is kept as untagged chunks.
+// Files whose symbols cannot be extracted fall back to ChunkTextLines.
+func ChunkCode(content, ext string, chunkSize, overlap int) []Chunk {
+	if chunkSize <= 0 {
+		chunkSize = 500
+	}
+	if overlap < 0 {
+		overlap = 100
+	}
+
+	extractor, ok := symbolExtractors[strings.ToLower(ext)]
+	if !ok {
+		return ChunkTextLines(content, chunkSize, overlap)
+	}
+	symbols, err := extractor.Extract(content)
+	if err != nil || len(symbols) == 0 {
+		return ChunkTextLines(content, chunkSize, overlap)
+	}
+
+	lineStarts := lineOffsets(content)
+	totalLines := len(lineStarts)
+	symbols = normalizeSymbols(symbols, totalLines)
+
+	// lineRange returns the byte range covering lines from..to (inclusive).
+	lineRange := func(from, to int) (int, int) {
+		if to >= totalLines {
+			return lineStarts[from-1], len(content)
+		}
+		return lineStarts[from-1], lineStarts[to]
+	}
+
+	var chunks []Chunk
+	emit := func(from, to int, name, kind string) {
+		start, end := lineRange(from, to)
+		if strings.TrimSpace(content[start:end]) == "" {
+			return
+		}
+		chunks = append(chunks, splitSegment(content[start:end], from, start, chunkSize, overlap, name, kind)...)
+	}
+
+	line := 1
+	for _, sym := range symbols {
+		if sym.StartLine > line {
+			emit(line, sym.StartLine-1, "", "")
+		}
+		emit(sym.StartLine, sym.EndLine, sym.Name, sym.Kind)
+		line = sym.EndLine + 1
+	}
+	if line <= totalLines {
+		emit(line, totalLines, "", "")
+	}
+
+	return chunks
+}
+
+// normalizeSymbols sorts symbols, clamps them to the file and removes overlaps
+// so that every line belongs to at most one symbol.
+func normalizeSymbols(symbols []Symbol, totalLines int) []Symbol {
+	sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].StartLine < symbols[j].StartLine })
+
+	var out []Symbol
+	next := 1
+	for _, s := range symbols {
+		if s.StartLine < next {
+			s.StartLine = next
+		}
+		if s.EndLine > totalLines {
+			s.EndLine = totalLines
+		}
+		if s.EndLine < s.StartLine {
+			continue
+		}
+		out = append(out, s)
+		next = s.EndLine + 1
+	}
+	return out
+}
+
+// splitSegment turns one region of the file, starting at firstLine and byte
+// offset firstByte, into chunks, splitting it with the text splitter when it
+// exceeds chunkSize tokens.
+func splitSegment(segment string, firstLine, firstByte, chunkSize, overlap int, name, kind string) []Chunk {
+	var pieces []string
+	if tokenLen(segment) <= chunkSize {
+		pieces = []string{segment}
+	} else {
+		pieces = recursiveSplit(segment, chunkSize, overlap, defaultSeparators)
+	}
+
+	chunks := locateChunks(segment, pieces, name, kind)
+	for i := range chunks {
+		chunks[i].StartLine += firstLine - 1
+		chunks[i].EndLine += firstLine - 1
+		if chunks[i].EndByte > 0 {
+			chunks[i].StartByte += firstByte
+			chunks[i].EndByte += firstByte
+		}
+	}
+	return chunks
+}
+
+// locateChunks finds each piece in text (in order, allowing overlap) and
+// records its line and byte range relative to text.
+func locateChunks(text string, pieces []string, name, kind string) []Chunk {
+	starts := lineOffsets(text)
+	chunks := make([]Chunk, 0, len(pieces))
+	cursor := 0
+	for _, p := range pieces {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		pos := strings.Index(text[cursor:], p)
+		found := pos >= 0
+		if !found {
+			pos = 0
+		}
+		start := cursor + pos
+		end := start + len(p)
+		if end > len(text) {
+			end = len(text)
+		}
+		chunk := Chunk{
+			Content:    p,
+			SymbolName: name,
+			SymbolKind: kind,
+			StartLine:  lineAt(starts, start),
+			EndLine:    lineAt(starts, max(start, end-1)),
+		}
+		// Only record a byte range when the piece really is that range
+		if found {
+			chunk.StartByte, chunk.EndByte = start, end
+		}
+		chunks = append(chunks, chunk)
+		if start+1 <= len(text) {
+			cursor = start + 1
+		}
+	}
+	return chunks
+}
+
+// lineOffsets returns the byte offset at which each line of text starts.
+func lineOffsets(text string) []int {
+	offsets := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' && i+1 < len(text) {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// lineAt returns the 1-based line containing byte offset pos.
+func lineAt(lineStarts []int, pos int) int {
+	return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > pos })
+}
@@ -0,0 +1,149 @@
+package rag
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileRef is a reference to a line of a file found in a question, such as a
+// stack frame or "handler.go:42". Path is as written, with backslashes
+// turned into slashes; Text is the reference as it appeared.
+type FileRef struct {
+	Path string
+	Line int
+	Text string
+}
+
+// maxFileRefs bounds the references taken from one question.
+const maxFileRefs = 20
+
+var (
+	// fileLineRef matches path:line with an optional :column, as in Go
+	// panics ("/src/app/main.go:42 +0x1d"), JS stack frames
+	// ("at f (webpack:///./src/a.ts:12:5)") and compiler errors.
+	fileLineRef = regexp.MustCompile(`((?:[A-Za-z][\w+.\-]*://|\b[A-Za-z]:)?[\w.@~+\-/\\]*[\w\-]\.[A-Za-z]\w{0,9}):(\d+)(?::\d+)?`)
+	// pythonFrame matches a Python traceback line.
+	pythonFrame = regexp.MustCompile(`File "([^"]+)", line (\d+)`)
+)
+
+// refExtensions lists the extensions, besides those with a symbol extractor,
+// that a bare file name needs for "name.ext:N" to count as a reference;
+// without it "example.com:443" would read as line 443 of example.com.
+var refExtensions = map[string]bool{
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true,
+	".rb": true, ".php": true, ".swift": true, ".scala": true, ".m": true,
+	".sh": true, ".sql": true, ".proto": true, ".vue": true, ".svelte": true,
+	".html": true, ".css": true, ".scss": true, ".json": true, ".yaml": true,
+	".yml": true, ".toml": true, ".xml": true, ".md": true, ".txt": true,
+	".tf": true, ".mod": true, ".gradle": true, ".ex": true, ".exs": true,
+	".erl": true, ".dart": true, ".lua": true, ".pl": true,
+}
+
+// ParseFileRefs returns the file:line references in text in order of
+// appearance, without duplicates. Line 0 is never a reference, and a name
+// without a directory must have a known source extension.
+func ParseFileRefs(text string) []FileRef {
+	type match struct {
+		start int
+		ref   FileRef
+	}
+	var matches []match
+	for _, re := range []*regexp.Regexp{pythonFrame, fileLineRef} {
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			line, err := strconv.Atoi(text[m[4]:m[5]])
+			if err != nil || line == 0 {
+				continue
+			}
+			p := cleanRefPath(text[m[2]:m[3]])
+			if re == fileLineRef && !strings.Contains(p, "/") && !isSourceName(p) {
+				continue // host:port, not file:line
+			}
+			matches = append(matches, match{m[0], FileRef{
+				Path: p,
+				Line: line,
+				Text: text[m[0]:m[1]],
+			}})
+		}
+	}
+	// Python frames come first above; restore the order of the text
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0 && matches[j].start < matches[j-1].start; j-- {
+			matches[j], matches[j-1] = matches[j-1], matches[j]
+		}
+	}
+
+	seen := make(map[string]bool)
+	var refs []FileRef
+	for _, m := range matches {
+		key := m.ref.Path + ":" + strconv.Itoa(m.ref.Line)
+		if seen[key] || m.ref.Path == "" {
+			continue
+		}
+		seen[key] = true
+		refs = append(refs, m.ref)
+		if len(refs) == maxFileRefs {
+			break
+		}
+	}
+	return refs
+}
+
+// isSourceName reports whether a bare file name has a source or config
+// file extension.
+func isSourceName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return HasSymbolExtractor(ext) || refExtensions[ext]
+}
+
+// cleanRefPath normalizes the path of a reference: slashes only, without
+// URL schemes, drive letters and leading "./" or "../".
+func cleanRefPath(p string) string {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if i := strings.Index(p, "://"); i >= 0 {
+		p = p[i+3:]
+	}
+	if len(p) > 2 && p[1] == ':' {
+		p = p[2:] // Windows drive
+	}
+	p = path.Clean(p)
+	for strings.HasPrefix(p, "../") {
+		p = p[3:]
+	}
+	if p == "." || p == ".." {
+		return ""
+	}
+	return p
+}
+
+// MatchRefPath returns the project path a reference's path names, comparing
+// them by trailing path segments so both absolute paths from another
+// machine and bare file names resolve; the longest shared suffix wins. ""
+// is returned when nothing matches or the best match is ambiguous.
+func MatchRefPath(refPath string, paths []string) string {
+	ref := strings.Split(strings.Trim(refPath, "/"), "/")
+	best, bestLen, tie := "", 0, false
+	for _, p := range paths {
+		parts := strings.Split(p, "/")
+		n := 0
+		for n < len(ref) && n < len(parts) && ref[len(ref)-1-n] == parts[len(parts)-1-n] {
+			n++
+		}
+		// Paths from another checkout ("/app/internal/a.go") must share at
+		// least a directory, or any same-named file would match
+		if n == 0 || (n < len(ref) && n < len(parts) && n < 2) {
+			continue
+		}
+		switch {
+		case n > bestLen:
+			best, bestLen, tie = p, n, false
+		case n == bestLen:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,90 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFileRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []FileRef
+	}{
+		{
+			name: "bare file name",
+			text: "what happens at handler.go:42?",
+			want: []FileRef{{Path: "handler.go", Line: 42, Text: "handler.go:42"}},
+		},
+		{
+			name: "go panic frame",
+			text: "/src/app/internal/chat.go:118 +0x1d",
+			want: []FileRef{{Path: "/src/app/internal/chat.go", Line: 118, Text: "/src/app/internal/chat.go:118"}},
+		},
+		{
+			name: "js frame with column and scheme",
+			text: "at send (webpack:///./src/api/client.ts:12:5)",
+			want: []FileRef{{Path: "/src/api/client.ts", Line: 12, Text: "webpack:///./src/api/client.ts:12:5"}},
+		},
+		{
+			name: "windows path",
+			text: `C:\work\app\main.py:7`,
+			want: []FileRef{{Path: "/work/app/main.py", Line: 7, Text: `C:\work\app\main.py:7`}},
+		},
+		{
+			name: "python traceback keeps text order",
+			text: "util.go:3 then\n  File \"app/views.py\", line 30, in index",
+			want: []FileRef{
+				{Path: "util.go", Line: 3, Text: "util.go:3"},
+				{Path: "app/views.py", Line: 30, Text: `File "app/views.py", line 30`},
+			},
+		},
+		{
+			name: "duplicates dropped",
+			text: "main.go:5 and again ./main.go:5",
+			want: []FileRef{{Path: "main.go", Line: 5, Text: "main.go:5"}},
+		},
+		{name: "line zero", text: "main.go:0", want: nil},
+		{name: "host and port", text: "connect to example.com:443 failed", want: nil},
+		{name: "url with port", text: "GET https://api.example.org:8443/v1/users", want: nil},
+		{name: "no line", text: "see main.go for details", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseFileRefs(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFileRefs(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRefPath(t *testing.T) {
+	paths := []string{
+		"cmd/server/main.go",
+		"cmd/rag-eval/main.go",
+		"internal/services/chat_service.go",
+		"internal/rag/chunker.go",
+		"frontend/src/api/client.ts",
+	}
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{name: "exact path", ref: "internal/rag/chunker.go", want: "internal/rag/chunker.go"},
+		{name: "unique bare name", ref: "chat_service.go", want: "internal/services/chat_service.go"},
+		{name: "absolute path of another checkout", ref: "/home/ci/app/internal/services/chat_service.go", want: "internal/services/chat_service.go"},
+		{name: "ambiguous bare name", ref: "main.go", want: ""},
+		{name: "directory disambiguates", ref: "/build/cmd/server/main.go", want: "cmd/server/main.go"},
+		{name: "only the name shared", ref: "/usr/lib/go/src/runtime/chunker.go", want: ""},
+		{name: "leading slash from a frame", ref: "/src/api/client.ts", want: "frontend/src/api/client.ts"},
+		{name: "unknown file", ref: "util.go", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchRefPath(tt.ref, paths); got != tt.want {
+				t.Errorf("MatchRefPath(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// identifier matches code identifiers, including dotted paths such as
+// rag.ChunkCode or os.Stdin.Read.
+var identifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*`)
+
+// SearchText returns the text a chunk's full-text index is built from: the
+// content itself followed by the parts of its compound identifiers
+// (HybridSearch, chunk_repo, rag.Chunk) and the character pairs of text in
+// scripts written without spaces, such as Thai. Text search parsers see
+// neither, so without them identifiers only match verbatim and a Thai
+// sentence becomes one lexeme.
+func SearchText(content string) string {
+	seen := make(map[string]bool)
+	var extra []string
+	add := func(terms []string) {
+		for _, t := range terms {
+			if key := strings.ToLower(t); !seen[key] {
+				seen[key] = true
+				extra = append(extra, t)
+			}
+		}
+	}
+	for _, id := range identifier.FindAllString(content, -1) {
+		if parts := identifierParts(id); len(parts) > 1 {
+			add(parts)
+		}
+	}
+	for _, run := range unspacedRuns(content) {
+		add(bigrams(run))
+	}
+	if len(extra) == 0 {
+		return content
+	}
+	return content + "\n" + strings.Join(extra, " ")
+}
+
+// SearchQuery prepares a query for matching against SearchText: compound
+// identifiers are replaced by their parts and unspaced text by its character
+// pairs, so "HybridSearch" also finds hybrid_search and prose mentioning a
+// hybrid search.
+func SearchQuery(query string) string {
+	query = identifier.ReplaceAllStringFunc(query, func(id string) string {
+		return strings.Join(identifierParts(id), " ")
+	})
+	for _, run := range unspacedRuns(query) {
+		if pairs := bigrams(run); len(pairs) > 1 {
+			query = strings.Replace(query, run, strings.Join(pairs, " "), 1)
+		}
+	}
+	return query
+}
+
+// quotedTerm matches text in double quotes or backticks.
+var quotedTerm = regexp.MustCompile("\"([^\"]+)\"|`([^`]+)`")
+
+// maxExactTerms bounds how many terms ExactTerms returns.
+const maxExactTerms = 5
+
+// ExactTerms returns the parts of a query worth matching literally: quoted
+// text ("connection refused", `max_retries`) and identifiers that are
+// clearly code because they are compound (HybridSearch, chunk_repo,
+// rag.Chunk). Plain words are left to vector and full-text search.
+func ExactTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(t string) {
+		t = strings.TrimSpace(t)
+		if len(t) >= 3 && !seen[t] && len(terms) < maxExactTerms {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	for _, m := range quotedTerm.FindAllStringSubmatch(query, -1) {
+		add(m[1] + m[2])
+	}
+	for _, id := range identifier.FindAllString(quotedTerm.ReplaceAllString(query, " "), -1) {
+		// Length 4 keeps abbreviations such as "e.g" out
+		if len(id) >= 4 && len(identifierParts(id)) > 1 {
+			add(id)
+		}
+	}
+	return terms
+}
+
+// identifierParts splits an identifier on dots, underscores and case
+// changes: "parseHTTPRequest_v2" gives parse, HTTP, Request, v2.
+func identifierParts(id string) []string {
+	var parts []string
+	for _, word := range strings.FieldsFunc(id, func(r rune) bool { return r == '.' || r == '_' }) {
+		runes := []rune(word)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			lowerToUpper := unicode.IsLower(prev) && unicode.IsUpper(cur)
+			// The last capital of an acronym starts the next word: HTTPServer
+			acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(cur) &&
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		parts = append(parts, string(runes[start:]))
+	}
+	return parts
+}
+
+// unspacedScripts are scripts whose words are not separated by spaces.
+var unspacedScripts = []*unicode.RangeTable{
+	unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+	unicode.Han, unicode.Hiragana, unicode.Katakana,
+}
+
+// unspacedRuns returns the runs of text in unspacedScripts.
+func unspacedRuns(text string) []string {
+	var runs []string
+	start := -1
+	for i, r := range text {
+		in := unicode.In(r, unspacedScripts...)
+		switch {
+		case in && start < 0:
+			start = i
+		case !in && start >= 0:
+			runs = append(runs, text[start:i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, text[start:])
+	}
+	return runs
+}
+
+// bigrams returns the overlapping character pairs of run, or run itself if
+// it is a single character.
+func bigrams(run string) []string {
+	runes := []rune(run)
+	if len(runes) < 2 {
+		return []string{run}
+	}
+	pairs := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		pairs = append(pairs, string(runes[i:i+2]))
+	}
+	return pairs
+}
@@ -0,0 +1,72 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "plain prose unchanged", content: "sessions expire after thirty minutes", want: "sessions expire after thirty minutes"},
+		{name: "camel case", content: "func HybridSearch()", want: "func HybridSearch()\nHybrid Search"},
+		{name: "acronym", content: "parseHTTPRequest_v2", want: "parseHTTPRequest_v2\nparse HTTP Request v2"},
+		{name: "dotted and snake case", content: "rag.Chunk in chunk_repo", want: "rag.Chunk in chunk_repo\nrag Chunk repo"},
+		{name: "thai bigrams", content: "กขค", want: "กขค\nกข ขค"},
+		{name: "single han character", content: "字 x", want: "字 x\n字"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SearchText(tt.content); got != tt.want {
+				t.Errorf("SearchText(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"how does HybridSearch rank", "how does Hybrid Search rank"},
+		{"chunk_repo insert", "chunk repo insert"},
+		{"検索 results", "検索 results"},
+		{"全文検索", "全文 文検 検索"},
+	}
+	for _, tt := range tests {
+		if got := SearchQuery(tt.query); got != tt.want {
+			t.Errorf("SearchQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExactTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "plain words", query: "how do sessions expire", want: nil},
+		{name: "quoted text", query: `where is "connection refused" logged`, want: []string{"connection refused"}},
+		{name: "backticks", query: "what sets `max_retries`", want: []string{"max_retries"}},
+		{name: "compound identifiers", query: "does HybridSearch call chunk_repo or rag.Chunk", want: []string{"HybridSearch", "chunk_repo", "rag.Chunk"}},
+		{name: "short quote and abbreviation", query: `a "db" lookup, e.g a cache`, want: nil},
+		{name: "quoted identifier once", query: "`HybridSearch` vs HybridSearch", want: []string{"HybridSearch"}},
+		{
+			name:  "bounded",
+			query: "aB_1 bC_2 cD_3 dE_4 eF_5 fG_6",
+			want:  []string{"aB_1", "bC_2", "cD_3", "dE_4", "eF_5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExactTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExactTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package rag
+
+import (
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Import kinds. The target of a KindGo import is the Go import path; a
+// KindGoModule entry records the module path a go.mod declares; KindPath
+// targets are project-relative paths without extension; KindPython targets
+// are absolute module paths with dots turned into slashes, matched against
+// the end of file paths since the source root is unknown.
+const (
+	KindGo       = "go"
+	KindGoModule = "gomod"
+	KindPath     = "path"
+	KindPython   = "py"
+)
+
+// Import is a dependency of a file on other files of the project, as far as
+// it can be told from the file alone. Line is 1-based.
+type Import struct {
+	Kind   string
+	Target string
+	Line   int
+}
+
+// ParseImports returns the imports of a Go, JavaScript/TypeScript or Python
+// file, or the module declaration of a go.mod file. filePath is the file's
+// project-relative path, against which relative imports are resolved. Only
+// relative JavaScript imports are kept: bare specifiers name packages.
+func ParseImports(content, filePath string) []Import {
+	if path.Base(filePath) == "go.mod" {
+		if m := goModule.FindStringSubmatchIndex(content); m != nil {
+			return []Import{{Kind: KindGoModule, Target: content[m[2]:m[3]], Line: lineOf(content, m[0])}}
+		}
+		return nil
+	}
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".go":
+		return goImports(content)
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx":
+		return jsImports(content, path.Dir(filePath))
+	case ".py":
+		return pythonImports(content, path.Dir(filePath))
+	}
+	return nil
+}
+
+var goModule = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+
+func goImports(content string) []Import {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	var imports []Import
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || p == "C" {
+			continue
+		}
+		imports = append(imports, Import{Kind: KindGo, Target: p, Line: fset.Position(spec.Pos()).Line})
+	}
+	return imports
+}
+
+// jsImport matches import/export ... from, side-effect imports, require()
+// and dynamic import() of a relative specifier.
+var jsImport = regexp.MustCompile(`(?:\b(?:import|export)\s[^'"` + "`" + `;]*?\bfrom\s*|\bimport\s*|\b(?:require|import)\s*\(\s*)['"](\.{1,2}/[^'"]*|\.{1,2})['"]`)
+
+func jsImports(content, dir string) []Import {
+	var imports []Import
+	for _, m := range jsImport.FindAllStringSubmatchIndex(content, -1) {
+		imports = append(imports, Import{
+			Kind:   KindPath,
+			Target: path.Join(dir, content[m[2]:m[3]]),
+			Line:   lineOf(content, m[2]),
+		})
+	}
+	return imports
+}
+
+var (
+	pyImport     = regexp.MustCompile(`^\s*import\s+(.+)$`)
+	pyFromImport = regexp.MustCompile(`^\s*from\s+(\.*)([\w.]*)\s+import\s+(.+)$`)
+)
+
+// pythonImports returns the modules a Python file imports. "from m import
+// a" yields both m and m.a, since a may be a submodule; targets that are
+// not files simply stay unresolved.
+func pythonImports(content, dir string) []Import {
+	lines := strings.Split(content, "\n")
+	var imports []Import
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if m := pyImport.FindStringSubmatch(line); m != nil {
+			for _, name := range pythonNames(m[1]) {
+				imports = append(imports, Import{Kind: KindPython, Target: strings.ReplaceAll(name, ".", "/"), Line: i + 1})
+			}
+			continue
+		}
+		m := pyFromImport.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		first := i
+		names := m[3]
+		// Parenthesized names may continue on the following lines
+		if strings.HasPrefix(strings.TrimSpace(names), "(") {
+			for !strings.Contains(names, ")") && i+1 < len(lines) {
+				i++
+				names += " " + lines[i]
+			}
+		}
+
+		dots, module := len(m[1]), strings.ReplaceAll(m[2], ".", "/")
+		kind, base := KindPython, module
+		if dots > 0 {
+			pkg := dir
+			for d := 1; d < dots; d++ {
+				pkg = path.Dir(pkg)
+			}
+			kind, base = KindPath, path.Join(pkg, module)
+		}
+		if module != "" {
+			imports = append(imports, Import{Kind: kind, Target: base, Line: first + 1})
+		}
+		for _, name := range pythonNames(strings.Trim(strings.TrimSpace(names), "()")) {
+			if name != "*" {
+				imports = append(imports, Import{Kind: kind, Target: path.Join(base, name), Line: first + 1})
+			}
+		}
+	}
+	return imports
+}
+
+// pythonNames splits an import list, dropping aliases and comments.
+func pythonNames(list string) []string {
+	if i := strings.Index(list, "#"); i >= 0 {
+		list = list[:i]
+	}
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		fields := strings.Fields(strings.Trim(strings.TrimSpace(part), "()"))
+		if len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
+
+// lineOf returns the 1-based line of content containing byte offset.
+func lineOf(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// ImportResolver maps imports to the project's files.
+type ImportResolver struct {
+	files   map[string]bool     // path -> exists
+	byDir   map[string][]string // directory -> Go files directly in it
+	byName  map[string][]string // module name (last segment) -> Python module paths
+	modules map[string]string   // go.mod directory -> module path
+}
+
+// NewImportResolver indexes the project's file paths and the go.mod module
+// declarations found among imports, keyed by the go.mod file's path.
+func NewImportResolver(paths []string, goModules map[string]string) *ImportResolver {
+	r := &ImportResolver{
+		files:   make(map[string]bool, len(paths)),
+		byDir:   make(map[string][]string),
+		byName:  make(map[string][]string),
+		modules: make(map[string]string, len(goModules)),
+	}
+	for modFile, module := range goModules {
+		r.modules[path.Dir(modFile)] = module
+	}
+	for _, p := range paths {
+		r.files[p] = true
+		switch {
+		case strings.HasSuffix(p, ".go") && !strings.HasSuffix(p, "_test.go"):
+			r.byDir[path.Dir(p)] = append(r.byDir[path.Dir(p)], p)
+		case strings.HasSuffix(p, ".py"):
+			module := strings.TrimSuffix(strings.TrimSuffix(p, ".py"), "/__init__")
+			r.byName[path.Base(module)] = append(r.byName[path.Base(module)], p)
+		}
+	}
+	return r
+}
+
+// pathExtensions are tried, in order, for KindPath targets.
+var pathExtensions = []string{
+	"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".py",
+	"/index.ts", "/index.tsx", "/index.js", "/index.jsx", "/__init__.py",
+}
+
+// Resolve returns the project files an import refers to: all non-test files
+// of an imported Go package, or the one file a path or Python module names.
+func (r *ImportResolver) Resolve(imp Import) []string {
+	switch imp.Kind {
+	case KindGo:
+		// The longest matching module path wins, for nested modules
+		var pkg, best string
+		for dir, module := range r.modules {
+			if (imp.Target == module || strings.HasPrefix(imp.Target, module+"/")) && len(module) > len(best) {
+				pkg, best = path.Join(dir, strings.TrimPrefix(imp.Target, module)), module
+			}
+		}
+		if best != "" {
+			return r.byDir[pkg]
+		}
+	case KindPath:
+		target := imp.Target
+		// TypeScript sources are imported by the name of their output
+		if ext := path.Ext(target); ext == ".js" || ext == ".jsx" || ext == ".mjs" {
+			if !r.files[target] {
+				target = strings.TrimSuffix(target, ext)
+			}
+		}
+		for _, ext := range pathExtensions {
+			if r.files[target+ext] {
+				return []string{target + ext}
+			}
+		}
+	case KindPython:
+		for _, p := range r.byName[path.Base(imp.Target)] {
+			module := strings.TrimSuffix(strings.TrimSuffix(p, ".py"), "/__init__")
+			if module == imp.Target || strings.HasSuffix(module, "/"+imp.Target) {
+				return []string{p}
+			}
+		}
+	}
+	return nil
+}
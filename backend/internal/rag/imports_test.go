@@ -0,0 +1,113 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		content  string
+		want     []Import
+	}{
+		{
+			name:     "go module",
+			filePath: "backend/go.mod",
+			content:  "module rag-chat-system\n\ngo 1.22\n",
+			want:     []Import{{Kind: KindGoModule, Target: "rag-chat-system", Line: 1}},
+		},
+		{
+			name:     "go imports skip cgo",
+			filePath: "cmd/server/main.go",
+			content:  "package main\n\nimport (\n\t\"C\"\n\t\"fmt\"\n\tdb \"rag-chat-system/internal/database\"\n)\n",
+			want: []Import{
+				{Kind: KindGo, Target: "fmt", Line: 5},
+				{Kind: KindGo, Target: "rag-chat-system/internal/database", Line: 6},
+			},
+		},
+		{name: "broken go", filePath: "a.go", content: "package", want: nil},
+		{
+			name:     "relative js only",
+			filePath: "src/pages/chat.tsx",
+			content:  "import React from 'react'\nimport { api } from '../api/client'\nimport './chat.css'\nconst u = require(\"./util\")\nconst l = await import('..')\n",
+			want: []Import{
+				{Kind: KindPath, Target: "src/api/client", Line: 2},
+				{Kind: KindPath, Target: "src/pages/chat.css", Line: 3},
+				{Kind: KindPath, Target: "src/pages/util", Line: 4},
+				{Kind: KindPath, Target: "src", Line: 5},
+			},
+		},
+		{
+			name:     "python",
+			filePath: "app/views/index.py",
+			content:  "import os, app.models as m\nfrom app.db import (\n    session,\n    engine,  # pool\n)\nfrom .. import settings\nfrom .forms import *\n",
+			want: []Import{
+				{Kind: KindPython, Target: "os", Line: 1},
+				{Kind: KindPython, Target: "app/models", Line: 1},
+				{Kind: KindPython, Target: "app/db", Line: 2},
+				{Kind: KindPython, Target: "app/db/session", Line: 2},
+				{Kind: KindPython, Target: "app/db/engine", Line: 2},
+				{Kind: KindPath, Target: "app/settings", Line: 6},
+				{Kind: KindPath, Target: "app/views/forms", Line: 7},
+			},
+		},
+		{name: "unsupported language", filePath: "README.md", content: "import x from './y'", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseImports(tt.content, tt.filePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseImports = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportResolverResolve(t *testing.T) {
+	r := NewImportResolver([]string{
+		"backend/go.mod",
+		"backend/internal/rag/chunker.go",
+		"backend/internal/rag/chunker_test.go",
+		"backend/internal/rag/imports.go",
+		"backend/tools/go.mod",
+		"backend/tools/gen/main.go",
+		"frontend/src/api/client.ts",
+		"frontend/src/components/index.tsx",
+		"frontend/src/legacy.js",
+		"app/models/__init__.py",
+		"app/db.py",
+		"scripts/db.py",
+	}, map[string]string{
+		"backend/go.mod":       "rag-chat-system",
+		"backend/tools/go.mod": "rag-chat-system/tools",
+	})
+
+	tests := []struct {
+		name string
+		imp  Import
+		want []string
+	}{
+		{
+			name: "go package without tests",
+			imp:  Import{Kind: KindGo, Target: "rag-chat-system/internal/rag"},
+			want: []string{"backend/internal/rag/chunker.go", "backend/internal/rag/imports.go"},
+		},
+		{name: "nested module wins", imp: Import{Kind: KindGo, Target: "rag-chat-system/tools/gen"}, want: []string{"backend/tools/gen/main.go"}},
+		{name: "other go module", imp: Import{Kind: KindGo, Target: "github.com/google/uuid"}, want: nil},
+		{name: "ts extension", imp: Import{Kind: KindPath, Target: "frontend/src/api/client"}, want: []string{"frontend/src/api/client.ts"}},
+		{name: "js name of ts source", imp: Import{Kind: KindPath, Target: "frontend/src/api/client.js"}, want: []string{"frontend/src/api/client.ts"}},
+		{name: "existing js file", imp: Import{Kind: KindPath, Target: "frontend/src/legacy.js"}, want: []string{"frontend/src/legacy.js"}},
+		{name: "directory index", imp: Import{Kind: KindPath, Target: "frontend/src/components"}, want: []string{"frontend/src/components/index.tsx"}},
+		{name: "python package", imp: Import{Kind: KindPython, Target: "app/models"}, want: []string{"app/models/__init__.py"}},
+		{name: "python module by full path", imp: Import{Kind: KindPython, Target: "scripts/db"}, want: []string{"scripts/db.py"}},
+		{name: "python module elsewhere", imp: Import{Kind: KindPython, Target: "os"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Resolve(tt.imp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%+v) = %v, want %v", tt.imp, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+
+	"rag-chat-system/internal/models"
+)
+
+// queryOperator matches inline scope operators such as path:services/,
+// ext:go, file:main.go or -path:"vendor dir", optionally negated with "-".
+var queryOperator = regexp.MustCompile(`(?:^|\s)(-?)(path|ext|file):("[^"]*"|\S+)`)
+
+// ParseQueryFilters removes inline scope operators from query and returns
+// the remaining text with the filter they describe. file: is path: for a
+// single file. If nothing but operators was given, the query is returned
+// unchanged so there is still something to search for.
+func ParseQueryFilters(query string) (string, models.SearchFilter) {
+	var f models.SearchFilter
+	for _, m := range queryOperator.FindAllStringSubmatch(query, -1) {
+		value := strings.Trim(m[3], `"`)
+		if value == "" {
+			continue
+		}
+		negate := m[1] == "-"
+		switch m[2] {
+		case "path", "file":
+			if negate {
+				f.ExcludePaths = append(f.ExcludePaths, value)
+			} else {
+				f.Paths = append(f.Paths, value)
+			}
+		case "ext":
+			if negate {
+				f.ExcludeExts = append(f.ExcludeExts, value)
+			} else {
+				f.Exts = append(f.Exts, value)
+			}
+		}
+	}
+	if f.Empty() {
+		return query, f
+	}
+
+	clean := strings.Join(strings.Fields(queryOperator.ReplaceAllString(query, " ")), " ")
+	if clean == "" {
+		return query, f
+	}
+	return clean, f
+}
@@ -0,0 +1,57 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+
+	"rag-chat-system/internal/models"
+)
+
+func TestParseQueryFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		want   string
+		filter models.SearchFilter
+	}{
+		{name: "no operators", query: "how are sessions stored", want: "how are sessions stored"},
+		{
+			name:   "path and ext",
+			query:  "path:services/ ext:go session expiry",
+			want:   "session expiry",
+			filter: models.SearchFilter{Paths: []string{"services/"}, Exts: []string{"go"}},
+		},
+		{
+			name:   "file is a path",
+			query:  "what does file:main.go start",
+			want:   "what does start",
+			filter: models.SearchFilter{Paths: []string{"main.go"}},
+		},
+		{
+			name:   "negated and quoted",
+			query:  `retry logic -path:"vendor dir" -ext:md`,
+			want:   "retry logic",
+			filter: models.SearchFilter{ExcludePaths: []string{"vendor dir"}, ExcludeExts: []string{"md"}},
+		},
+		{
+			name:   "operators only keep the query",
+			query:  "path:internal/rag",
+			want:   "path:internal/rag",
+			filter: models.SearchFilter{Paths: []string{"internal/rag"}},
+		},
+		{name: "empty value ignored", query: `path:"" chunker`, want: `path:"" chunker`},
+		{name: "operator inside a word", query: "read the xpath:foo docs", want: "read the xpath:foo docs"},
+		{name: "unknown operator", query: "lang:go chunker", want: "lang:go chunker"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, filter := ParseQueryFilters(tt.query)
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(filter, tt.filter) {
+				t.Errorf("filter = %+v, want %+v", filter, tt.filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package rag
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RepoMapEntry is a file or directory of a project, by project-relative
+// path, with its generated summary if there is one.
+type RepoMapEntry struct {
+	Path    string
+	IsDir   bool
+	Summary string
+}
+
+const (
+	// repoMapFilesPerDir bounds the files listed by name under a directory.
+	repoMapFilesPerDir = 8
+	// repoMapDepth bounds how deep directories are expanded; deeper ones
+	// are only counted.
+	repoMapDepth = 6
+)
+
+// RenderRepoMap renders entries as an indented tree: each directory with its
+// file count and the first sentence of its summary, followed by its
+// subdirectories and up to repoMapFilesPerDir of its files. The result is
+// cut to maxTokens.
+func RenderRepoMap(entries []RepoMapEntry, maxTokens int) string {
+	type dir struct {
+		summary string
+		dirs    []string
+		files   []RepoMapEntry
+		total   int // files below, at any depth
+	}
+	dirs := map[string]*dir{"": {}}
+	var ensure func(p string) *dir
+	ensure = func(p string) *dir {
+		if d, ok := dirs[p]; ok {
+			return d
+		}
+		d := &dir{}
+		dirs[p] = d
+		parent := ensure(parentDir(p))
+		parent.dirs = append(parent.dirs, p)
+		return d
+	}
+	for _, e := range entries {
+		if e.IsDir {
+			ensure(e.Path).summary = e.Summary
+			continue
+		}
+		parent := parentDir(e.Path)
+		d := ensure(parent)
+		d.files = append(d.files, e)
+		for p := parent; ; p = parentDir(p) {
+			dirs[p].total++
+			if p == "" {
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	var render func(p string, depth int)
+	render = func(p string, depth int) {
+		d := dirs[p]
+		indent := strings.Repeat("  ", depth)
+		sort.Strings(d.dirs)
+		for _, sub := range d.dirs {
+			sd := dirs[sub]
+			fmt.Fprintf(&b, "%s%s/ (%d files)", indent, path.Base(sub), sd.total)
+			if s := firstSentence(sd.summary); s != "" {
+				b.WriteString(" - " + s)
+			}
+			b.WriteString("\n")
+			if depth+1 < repoMapDepth {
+				render(sub, depth+1)
+			}
+		}
+		sort.Slice(d.files, func(i, j int) bool { return d.files[i].Path < d.files[j].Path })
+		for i, f := range d.files {
+			if i == repoMapFilesPerDir {
+				fmt.Fprintf(&b, "%s+%d more files\n", indent, len(d.files)-i)
+				break
+			}
+			b.WriteString(indent + path.Base(f.Path))
+			if s := firstSentence(f.Summary); s != "" {
+				b.WriteString(" - " + s)
+			}
+			b.WriteString("\n")
+		}
+	}
+	render("", 0)
+	return TruncateTokens(strings.TrimRight(b.String(), "\n"), maxTokens)
+}
+
+// parentDir returns the directory of a project-relative path, "" for the root.
+func parentDir(p string) string {
+	if d := path.Dir(p); d != "." && d != "/" {
+		return d
+	}
+	return ""
+}
+
+// firstSentence returns the first sentence of s on one line.
+func firstSentence(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for i := 0; i < len(s)-1; i++ {
+		if (s[i] == '.' || s[i] == '!' || s[i] == '?') && s[i+1] == ' ' {
+			return s[:i+1]
+		}
+	}
+	return s
+}
+
+// structuralQuestion matches questions about how a project is laid out
+// rather than about a specific piece of code.
+// Lookups such as "where is HybridSearch defined?" are not structural; the
+// chunks defining the name answer them.
+var structuralQuestion = regexp.MustCompile(`(?i)\b(structure[ds]?|architecture|organi[sz](ed|ation)|layout|high[- ]level|big picture|overview of the (project|repo(sitory)?|code ?base)|repo(sitory)? map|(which|what) (files?|folders?|director(y|ies)|packages?|modules?|components?)|entry ?points?|walk me through|how is .* (laid out|split|structured))\b`)
+
+// IsStructuralQuestion reports whether a question is about the project's
+// organization, which the repo map answers better than a few chunks.
+func IsStructuralQuestion(q string) bool {
+	return structuralQuestion.MatchString(q)
+}
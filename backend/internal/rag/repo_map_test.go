@@ -0,0 +1,66 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsStructuralQuestion(t *testing.T) {
+	tests := []struct {
+		q    string
+		want bool
+	}{
+		{"How is the project structured?", true},
+		{"Give me an overview of the codebase", true},
+		{"What's the high-level architecture?", true},
+		{"Which packages handle ingestion?", true},
+		{"What are the entry points?", true},
+		{"Walk me through the repo", true},
+		{"How is the backend laid out?", true},
+		{"Where is HybridSearch defined?", false},
+		{"Where does the chat handler stream tokens?", false},
+		{"Why does the codebase fail to build on Windows?", false},
+		{"Give an overview of the retry logic", false},
+		{"What does ChunkCode return for empty files?", false},
+		{"Fix the nil pointer in sendMessage", false},
+	}
+	for _, tt := range tests {
+		if got := IsStructuralQuestion(tt.q); got != tt.want {
+			t.Errorf("IsStructuralQuestion(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestRenderRepoMap(t *testing.T) {
+	requireTokenizer(t)
+
+	entries := []RepoMapEntry{
+		{Path: "cmd", IsDir: true},
+		{Path: "cmd/server", IsDir: true, Summary: "HTTP server entry point. Wires all services."},
+		{Path: "cmd/server/main.go", Summary: "Starts Echo."},
+		{Path: "internal", IsDir: true},
+		{Path: "internal/rag", IsDir: true, Summary: "Chunking and prompts."},
+		{Path: "internal/rag/chunker.go"},
+		{Path: "go.mod"},
+	}
+	want := strings.Join([]string{
+		"cmd/ (1 files)",
+		"  server/ (1 files) - HTTP server entry point.",
+		"    main.go - Starts Echo.",
+		"internal/ (1 files)",
+		"  rag/ (1 files) - Chunking and prompts.",
+		"    chunker.go",
+		"go.mod",
+	}, "\n")
+	if got := RenderRepoMap(entries, 1000); got != want {
+		t.Errorf("RenderRepoMap() =\n%s\nwant\n%s", got, want)
+	}
+
+	var many []RepoMapEntry
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		many = append(many, RepoMapEntry{Path: "pkg/" + name + ".go"})
+	}
+	if got := RenderRepoMap(many, 1000); !strings.HasSuffix(got, "  +2 more files") {
+		t.Errorf("RenderRepoMap() of 10 files = %q, want the last 2 elided", got)
+	}
+}
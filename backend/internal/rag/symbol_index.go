@@ -0,0 +1,158 @@
+package rag
+
+import "strings"
+
+// SymbolIndexer is implemented by extractors that can report every
+// declaration of a file for the symbol index, including class members,
+// with their signatures.
+type SymbolIndexer interface {
+	Index(content string) ([]Symbol, error)
+}
+
+// IndexSymbols returns the declarations of a source file for the symbol
+// index, or nil if ext has no extractor or the file does not parse.
+// Extractors that are not SymbolIndexers report their top-level symbols.
+func IndexSymbols(content, ext string) []Symbol {
+	e, ok := symbolExtractors[strings.ToLower(ext)]
+	if !ok {
+		return nil
+	}
+	var symbols []Symbol
+	var err error
+	if idx, ok := e.(SymbolIndexer); ok {
+		symbols, err = idx.Index(content)
+	} else {
+		symbols, err = e.Extract(content)
+	}
+	if err != nil {
+		return nil
+	}
+	return symbols
+}
+
+// maxQueryIdentifiers bounds how many names QueryIdentifiers returns.
+const maxQueryIdentifiers = 10
+
+// QueryIdentifiers returns the names in a query that probably refer to code
+// symbols: quoted words, compound identifiers (HybridSearch, chunk_repo),
+// names followed by parentheses (run()) and capitalized words past the
+// first. Dotted paths give their last part, so rag.ChunkCode looks up
+// ChunkCode.
+func QueryIdentifiers(query string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(id string) {
+		if i := strings.LastIndex(id, "."); i >= 0 {
+			id = id[i+1:]
+		}
+		if len(id) >= 3 && !seen[id] && len(names) < maxQueryIdentifiers {
+			seen[id] = true
+			names = append(names, id)
+		}
+	}
+	for _, m := range quotedTerm.FindAllStringSubmatch(query, -1) {
+		if t := strings.TrimSuffix(strings.TrimSpace(m[1]+m[2]), "()"); identifier.FindString(t) == t {
+			add(t)
+		}
+	}
+	rest := quotedTerm.ReplaceAllString(query, " ")
+	for i, loc := range identifier.FindAllStringIndex(rest, -1) {
+		id := rest[loc[0]:loc[1]]
+		called := strings.HasPrefix(rest[loc[1]:], "(")
+		capitalized := i > 0 && id[0] >= 'A' && id[0] <= 'Z'
+		if called || capitalized || len(identifierParts(id)) > 1 {
+			add(id)
+		}
+	}
+	return names
+}
+
+// signatureOf returns a declaration line as a signature, without the
+// opening brace or colon that starts its body, or the body itself when it
+// is on the same line.
+func signatureOf(line string) string {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, ") {"); i >= 0 && strings.HasSuffix(line, "}") {
+		line = line[:i+1]
+	}
+	line = strings.TrimSpace(strings.TrimSuffix(line, "{"))
+	return strings.TrimSuffix(line, ":")
+}
+
+// memberKeywords are statements a member pattern must not take for a method.
+var memberKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "function": true, "new": true, "super": true,
+}
+
+// Index reports the top-level declarations found by Extract, starting at
+// their declaration line, and the members of their classes when Members is
+// set.
+func (h HeuristicExtractor) Index(content string) ([]Symbol, error) {
+	top, err := h.Extract(content)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(content, "\n")
+
+	var symbols []Symbol
+	for _, sym := range top {
+		decl := sym.StartLine - 1
+		for decl < sym.EndLine-1 {
+			if _, _, ok := h.match(lines[decl]); ok {
+				break
+			}
+			decl++
+		}
+		sym.StartLine = decl + 1
+		sym.Signature = signatureOf(lines[decl])
+		symbols = append(symbols, sym)
+		if h.Members != nil && sym.Kind == "class" {
+			symbols = append(symbols, h.members(lines, decl, sym.EndLine-1, sym.Name)...)
+		}
+	}
+	return symbols, nil
+}
+
+// members finds the methods declared directly in the class spanning lines
+// decl..end: lines matching Members at the indentation of the class body.
+func (h HeuristicExtractor) members(lines []string, decl, end int, class string) []Symbol {
+	var symbols []Symbol
+	bodyIndent := -1
+	for i := decl + 1; i <= end && i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || trimmed == "}" || hasAnyPrefix(trimmed, h.LeadingPrefixes) {
+			continue
+		}
+		if bodyIndent < 0 {
+			bodyIndent = indentOf(lines[i])
+		}
+		if indentOf(lines[i]) != bodyIndent {
+			continue
+		}
+		m := h.Members.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		name := m[h.Members.SubexpIndex("name")]
+		if memberKeywords[name] {
+			continue
+		}
+		var last int
+		if h.Indented {
+			last = indentBlockEnd(lines, i)
+		} else {
+			last = braceBlockEnd(lines, i, end)
+		}
+		symbols = append(symbols, Symbol{
+			Name:      name,
+			Kind:      "method",
+			StartLine: i + 1,
+			EndLine:   min(last, end) + 1,
+			Container: class,
+			Signature: signatureOf(lines[i]),
+		})
+		i = last
+	}
+	return symbols
+}
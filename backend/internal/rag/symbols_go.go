@@ -0,0 +1,201 @@
+package rag
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+// goExtractor finds top-level functions, methods, types and value
+// declarations using the standard Go parser.
+type goExtractor struct{}
+
+func (goExtractor) Extract(content string) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			name, kind := d.Name.Name, "function"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name, kind = receiverName(d.Recv.List[0].Type)+"."+name, "method"
+			}
+			symbols = append(symbols, Symbol{Name: name, Kind: kind, StartLine: line(start), EndLine: line(d.End())})
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			name, kind := genDeclName(d)
+			if name == "" {
+				continue
+			}
+			symbols = append(symbols, Symbol{Name: name, Kind: kind, StartLine: line(start), EndLine: line(d.End())})
+		}
+	}
+	return symbols, nil
+}
+
+// Index reports every function, method, type, const and var, one per
+// spec of grouped declarations, plus the methods of interfaces. Line ranges
+// leave out doc comments.
+func (goExtractor) Index(content string) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+	format := func(node any) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			return ""
+		}
+		return strings.Join(strings.Fields(buf.String()), " ")
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := Symbol{
+				Name:      d.Name.Name,
+				Kind:      "function",
+				StartLine: line(d.Pos()),
+				EndLine:   line(d.End()),
+				Signature: format(&ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type}),
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				sym.Kind, sym.Container = "method", receiverName(d.Recv.List[0].Type)
+			}
+			symbols = append(symbols, sym)
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				// An ungrouped declaration starts at its keyword
+				start := spec.Pos()
+				if !d.Lparen.IsValid() {
+					start = d.Pos()
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					sym := Symbol{Name: spec.Name.Name, Kind: "type", StartLine: line(start), EndLine: line(spec.End())}
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						sym.Kind, sym.Signature = "struct", "type "+spec.Name.Name+" struct"
+					case *ast.InterfaceType:
+						sym.Kind, sym.Signature = "interface", "type "+spec.Name.Name+" interface"
+						symbols = append(symbols, sym)
+						symbols = append(symbols, interfaceMethods(t, spec.Name.Name, line, format)...)
+						continue
+					default:
+						sym.Signature = "type " + format(spec)
+					}
+					symbols = append(symbols, sym)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						sig := d.Tok.String() + " " + name.Name
+						if spec.Type != nil {
+							sig += " " + format(spec.Type)
+						}
+						symbols = append(symbols, Symbol{
+							Name:      name.Name,
+							Kind:      d.Tok.String(),
+							StartLine: line(start),
+							EndLine:   line(spec.End()),
+							Signature: sig,
+						})
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// interfaceMethods returns the methods declared in an interface type.
+func interfaceMethods(t *ast.InterfaceType, iface string, line func(token.Pos) int, format func(any) string) []Symbol {
+	var symbols []Symbol
+	for _, f := range t.Methods.List {
+		fn, ok := f.Type.(*ast.FuncType)
+		if !ok {
+			continue // embedded interface or type constraint
+		}
+		for _, name := range f.Names {
+			symbols = append(symbols, Symbol{
+				Name:      name.Name,
+				Kind:      "method",
+				StartLine: line(f.Pos()),
+				EndLine:   line(f.End()),
+				Container: iface,
+				Signature: name.Name + strings.TrimPrefix(format(fn), "func"),
+			})
+		}
+	}
+	return symbols
+}
+
+// genDeclName names a type/const/var declaration after its first spec. Single
+// type specs are refined to "struct" or "interface".
+func genDeclName(d *ast.GenDecl) (string, string) {
+	if len(d.Specs) == 0 {
+		return "", ""
+	}
+	switch spec := d.Specs[0].(type) {
+	case *ast.TypeSpec:
+		kind := "type"
+		if len(d.Specs) == 1 {
+			switch spec.Type.(type) {
+			case *ast.StructType:
+				kind = "struct"
+			case *ast.InterfaceType:
+				kind = "interface"
+			}
+		}
+		return spec.Name.Name, kind
+	case *ast.ValueSpec:
+		if len(spec.Names) == 0 {
+			return "", ""
+		}
+		return spec.Names[0].Name, d.Tok.String()
+	}
+	return "", ""
+}
+
+// receiverName returns the base type name of a method receiver, stripping
+// pointers and type parameters.
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
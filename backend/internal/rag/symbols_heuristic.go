@@ -0,0 +1,219 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SymbolPattern matches the first line of a declaration. The pattern must
+// have a "name" group and may have a "kind" group overriding Kind.
+type SymbolPattern struct {
+	Kind   string
+	Regexp *regexp.Regexp
+}
+
+// HeuristicExtractor finds top-level declarations in languages without a
+// parser in the standard library. Declarations are located with line-anchored
+// patterns and end where their braces balance or, when Indented is set, where
+// the indentation returns to the declaration's level.
+type HeuristicExtractor struct {
+	Patterns []SymbolPattern
+	Indented bool
+	// LeadingPrefixes marks lines (comments, decorators, attributes) directly
+	// above a declaration that belong to it.
+	LeadingPrefixes []string
+	// Members matches the first line of a method inside a class, with a
+	// "name" group; it is only used by Index.
+	Members *regexp.Regexp
+}
+
+func (h HeuristicExtractor) Extract(content string) ([]Symbol, error) {
+	lines := strings.Split(content, "\n")
+
+	var symbols []Symbol
+	for i := 0; i < len(lines); i++ {
+		name, kind, ok := h.match(lines[i])
+		if !ok {
+			continue
+		}
+
+		var end int
+		if h.Indented {
+			end = indentBlockEnd(lines, i)
+		} else {
+			end = braceBlockEnd(lines, i, h.nextMatch(lines, i+1))
+		}
+
+		start := i
+		for start > 0 && hasAnyPrefix(strings.TrimSpace(lines[start-1]), h.LeadingPrefixes) {
+			start--
+		}
+		if len(symbols) > 0 && start < symbols[len(symbols)-1].EndLine {
+			start = symbols[len(symbols)-1].EndLine
+		}
+
+		symbols = append(symbols, Symbol{Name: name, Kind: kind, StartLine: start + 1, EndLine: end + 1})
+		i = end
+	}
+	return symbols, nil
+}
+
+func (h HeuristicExtractor) match(line string) (string, string, bool) {
+	for _, p := range h.Patterns {
+		m := p.Regexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name := m[p.Regexp.SubexpIndex("name")]
+		kind := p.Kind
+		if idx := p.Regexp.SubexpIndex("kind"); idx >= 0 && m[idx] != "" {
+			kind = m[idx]
+		}
+		return name, kind, true
+	}
+	return "", "", false
+}
+
+// nextMatch returns the index of the next declaration line at or after from,
+// or len(lines) if there is none.
+func (h HeuristicExtractor) nextMatch(lines []string, from int) int {
+	for i := from; i < len(lines); i++ {
+		if _, _, ok := h.match(lines[i]); ok {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// braceBlockEnd returns the line on which the block opened at or after start
+// closes. Declarations without a body end at the first line ending in ';',
+// or just before the next declaration.
+func braceBlockEnd(lines []string, start, next int) int {
+	depth, opened := 0, false
+	var quote byte
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			if quote != 0 {
+				if c == '\\' {
+					j++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			}
+			switch {
+			case c == '/' && j+1 < len(line) && line[j+1] == '/':
+				j = len(line)
+			case c == '"' || c == '`':
+				quote = c
+			case c == '\'' && strings.IndexByte(line[j+1:], '\'') >= 0:
+				quote = c
+			case c == '{':
+				depth++
+				opened = true
+			case c == '}':
+				depth--
+			}
+		}
+		// Only template literals may span lines.
+		if quote != '`' {
+			quote = 0
+		}
+
+		if opened && depth <= 0 {
+			return i
+		}
+		if !opened {
+			if strings.HasSuffix(strings.TrimSpace(line), ";") {
+				return i
+			}
+			if i+1 >= next {
+				return lastContentLine(lines, start, i)
+			}
+		}
+	}
+	return lastContentLine(lines, start, len(lines)-1)
+}
+
+// indentBlockEnd returns the last line of an indentation-delimited block.
+func indentBlockEnd(lines []string, start int) int {
+	base := indentOf(lines[start])
+	end := start
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if indentOf(lines[i]) <= base && !hasAnyPrefix(trimmed, []string{")", "]", "}"}) {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+func lastContentLine(lines []string, start, end int) int {
+	for end > start && strings.TrimSpace(lines[end]) == "" {
+		end--
+	}
+	return end
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	if s == "" {
+		return false
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+var jsExtractor = HeuristicExtractor{
+	Patterns: []SymbolPattern{
+		{Kind: "function", Regexp: regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(?P<name>[A-Za-z_$][\w$]*)`)},
+		{Kind: "class", Regexp: regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{Kind: "function", Regexp: regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+(?P<name>[A-Za-z_$][\w$]*)\s*(?::[^=]*)?=\s*(?:async\s+)?(?:function\b|\(|[A-Za-z_$][\w$]*\s*=>)`)},
+		{Kind: "interface", Regexp: regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?interface\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{Kind: "type", Regexp: regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?type\s+(?P<name>[A-Za-z_$][\w$]*)\b[^=]*=`)},
+		{Kind: "enum", Regexp: regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+	},
+	LeadingPrefixes: []string{"//", "/*", "*", "@"},
+	Members:         regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|readonly|async|override|abstract|get|set)\s+)*\*?\s*(?P<name>#?[A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*\(`),
+}
+
+var pythonExtractor = HeuristicExtractor{
+	Patterns: []SymbolPattern{
+		{Kind: "function", Regexp: regexp.MustCompile(`^(?:async\s+)?def\s+(?P<name>\w+)`)},
+		{Kind: "class", Regexp: regexp.MustCompile(`^class\s+(?P<name>\w+)`)},
+	},
+	Indented:        true,
+	LeadingPrefixes: []string{"#", "@"},
+	Members:         regexp.MustCompile(`^\s+(?:async\s+)?def\s+(?P<name>\w+)`),
+}
+
+var rustExtractor = HeuristicExtractor{
+	Patterns: []SymbolPattern{
+		{Kind: "function", Regexp: regexp.MustCompile(`^(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+(?P<name>\w+)`)},
+		{Regexp: regexp.MustCompile(`^(?:pub(?:\([^)]*\))?\s+)?(?P<kind>struct|enum|trait|union)\s+(?P<name>\w+)`)},
+		{Kind: "impl", Regexp: regexp.MustCompile(`^(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?:[\w:<>, ]+\s+for\s+)?(?P<name>[\w:]+)`)},
+		{Kind: "module", Regexp: regexp.MustCompile(`^(?:pub(?:\([^)]*\))?\s+)?mod\s+(?P<name>\w+)\s*\{`)},
+	},
+	LeadingPrefixes: []string{"//", "/*", "*", "#"},
+}
+
+var javaExtractor = HeuristicExtractor{
+	Patterns: []SymbolPattern{
+		{Regexp: regexp.MustCompile(`^(?:(?:public|private|protected|internal|abstract|final|static|sealed|partial|open|data)\s+)*(?P<kind>class|interface|enum|record|object)\s+(?P<name>\w+)`)},
+		{Kind: "function", Regexp: regexp.MustCompile(`^(?:(?:public|private|internal|suspend|inline)\s+)*fun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(?P<name>\w+)`)},
+	},
+	LeadingPrefixes: []string{"//", "/*", "*", "@", "["},
+}
@@ -18,15 +18,18 @@ func NewChatRepo(db *pgxpool.Pool) *ChatRepo {
 
 func (r *ChatRepo) Create(ctx context.Context, c *models.Chat) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO chats (id, title, project_ids) VALUES ($1, $2, $3)`,
-		c.ID, c.Title, c.ProjectIDs,
+		`INSERT INTO chats (id, title, project_ids, model, temperature, query_rewrite, retrieval_strategy, verify)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)`,
+		c.ID, c.Title, c.ProjectIDs, c.Model, c.Temperature, c.QueryRewrite, c.RetrievalStrategy, c.Verify,
 	)
 	return err
 }
 
 func (r *ChatRepo) List(ctx context.Context) ([]models.Chat, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, title, COALESCE(project_ids, '{}'), created_at FROM chats ORDER BY created_at DESC`,
+		`SELECT id, title, COALESCE(project_ids, '{}'), COALESCE(model, ''), temperature, COALESCE(query_rewrite, TRUE),
+		        COALESCE(retrieval_strategy, 'single'), COALESCE(verify, FALSE), created_at
+		 FROM chats ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -36,7 +39,7 @@ func (r *ChatRepo) List(ctx context.Context) ([]models.Chat, error) {
 	var chats []models.Chat
 	for rows.Next() {
 		var c models.Chat
-		if err := rows.Scan(&c.ID, &c.Title, &c.ProjectIDs, &c.CreatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.Title, &c.ProjectIDs, &c.Model, &c.Temperature, &c.QueryRewrite, &c.RetrievalStrategy, &c.Verify, &c.CreatedAt); err != nil {
 			return nil, err
 		}
 		if c.ProjectIDs == nil {
@@ -47,6 +50,28 @@ func (r *ChatRepo) List(ctx context.Context) ([]models.Chat, error) {
 	return chats, nil
 }
 
+func (r *ChatRepo) GetByID(ctx context.Context, id string) (*models.Chat, error) {
+	var c models.Chat
+	err := r.db.QueryRow(ctx,
+		`SELECT id, title, COALESCE(project_ids, '{}'), COALESCE(model, ''), temperature, COALESCE(query_rewrite, TRUE),
+		        COALESCE(retrieval_strategy, 'single'), COALESCE(verify, FALSE), created_at
+		 FROM chats WHERE id=$1`, id,
+	).Scan(&c.ID, &c.Title, &c.ProjectIDs, &c.Model, &c.Temperature, &c.QueryRewrite, &c.RetrievalStrategy, &c.Verify, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateSettings saves a chat's title and generation settings.
+func (r *ChatRepo) UpdateSettings(ctx context.Context, c *models.Chat) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE chats SET title=$2, model=NULLIF($3, ''), temperature=$4, query_rewrite=$5, retrieval_strategy=$6, verify=$7 WHERE id=$1`,
+		c.ID, c.Title, c.Model, c.Temperature, c.QueryRewrite, c.RetrievalStrategy, c.Verify,
+	)
+	return err
+}
+
 func (r *ChatRepo) UpdateTitle(ctx context.Context, id, title string) error {
 	_, err := r.db.Exec(ctx, `UPDATE chats SET title=$1 WHERE id=$2`, title, id)
 	return err
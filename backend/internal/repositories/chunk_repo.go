@@ -3,9 +3,14 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	pgvector "github.com/pgvector/pgvector-go"
+
+	"rag-chat-system/internal/models"
 )
 
 type ChunkRepo struct {
@@ -16,67 +21,170 @@ func NewChunkRepo(db *pgxpool.Pool) *ChunkRepo {
 	return &ChunkRepo{db: db}
 }
 
-func (r *ChunkRepo) Create(ctx context.Context, id, projectID, fileID, content string, embedding []float32, fileName, fileExt string) error {
-	vec := pgvector.NewVector(embedding)
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO document_chunks (id, project_id, file_id, content, embedding, tsv, file_name, file_ext)
-		 VALUES ($1, $2, $3, $4, $5, to_tsvector('english', $4), $6, $7)`,
-		id, projectID, fileID, content, vec, fileName, fileExt,
+// ReplaceByFileID swaps all chunks of a file for the given ones in a single
+// transaction. Rows are streamed with COPY into a staging table so the tsvector
+// and vector casts happen server-side in one INSERT. ftsLanguage is the text
+// search configuration the tsvector is built from each chunk's SearchText with.
+func (r *ChunkRepo) ReplaceByFileID(ctx context.Context, fileID, ftsLanguage string, chunks []*models.DocumentChunk) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM document_chunks WHERE file_id=$1`, fileID); err != nil {
+		return fmt.Errorf("delete old chunks: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `CREATE TEMP TABLE chunk_stage (
+			id TEXT, project_id TEXT, file_id TEXT, file_path TEXT, file_name TEXT, file_ext TEXT,
+			content TEXT, content_hash TEXT, symbol_name TEXT, symbol_kind TEXT, start_line INT, end_line INT,
+			ordinal INT, start_byte INT, end_byte INT, embedding TEXT, embedding_model TEXT, search_text TEXT
+		) ON COMMIT DROP`); err != nil {
+		return fmt.Errorf("create stage table: %w", err)
+	}
+
+	rows := make([][]interface{}, len(chunks))
+	for i, c := range chunks {
+		rows[i] = []interface{}{
+			c.ID, c.ProjectID, c.FileID, c.FilePath, c.FileName, c.FileExt,
+			c.Content, c.ContentHash, c.SymbolName, c.SymbolKind, c.StartLine, c.EndLine,
+			c.Ordinal, c.StartByte, c.EndByte, pgvector.NewVector(c.Embedding).String(), c.EmbeddingModel, c.SearchText,
+		}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"chunk_stage"},
+		[]string{"id", "project_id", "file_id", "file_path", "file_name", "file_ext",
+			"content", "content_hash", "symbol_name", "symbol_kind", "start_line", "end_line",
+			"ordinal", "start_byte", "end_byte", "embedding", "embedding_model", "search_text"},
+		pgx.CopyFromRows(rows),
 	)
-	return err
+	if err != nil {
+		return fmt.Errorf("copy chunks: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO document_chunks (id, project_id, file_id, content, content_hash, embedding, embedding_model, tsv, fts_config,
+			file_path, file_name, file_ext, symbol_name, symbol_kind, start_line, end_line, ordinal, start_byte, end_byte)
+		SELECT id::uuid, project_id::uuid, file_id::uuid, content, content_hash, embedding::vector, embedding_model,
+			to_tsvector($1::regconfig, COALESCE(NULLIF(search_text, ''), content)), $1, file_path, file_name, file_ext, NULLIF(symbol_name, ''), NULLIF(symbol_kind, ''),
+			start_line, end_line, ordinal, start_byte, end_byte
+		FROM chunk_stage`, ftsLanguage); err != nil {
+		return fmt.Errorf("insert chunks: %w", err)
+	}
+
+	return tx.Commit(ctx)
 }
 
-// HybridSearch combines vector similarity and full-text search using Reciprocal Rank Fusion (RRF).
-func (r *ChunkRepo) HybridSearch(ctx context.Context, embedding []float32, query string, projectIDs []string, limit int) ([]string, error) {
-	vec := pgvector.NewVector(embedding)
+// ChunkText is a chunk's content, as needed to rebuild its full-text index.
+type ChunkText struct {
+	ID      string
+	Content string
+}
 
-	var sql string
-	var args []interface{}
+// ListStaleFTS returns up to limit chunks of a project whose tsvector was not
+// built with ftsLanguage from pre-tokenized text, in ID order.
+func (r *ChunkRepo) ListStaleFTS(ctx context.Context, projectID, ftsLanguage string, limit int) ([]ChunkText, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, COALESCE(content, '') FROM document_chunks
+		WHERE project_id = $1 AND fts_config IS DISTINCT FROM $2
+		ORDER BY id
+		LIMIT $3`, projectID, ftsLanguage, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list stale chunks: %w", err)
+	}
+	defer rows.Close()
 
-	if len(projectIDs) > 0 {
-		sql = `
-		WITH vector_ranked AS (
-			SELECT id, content, ROW_NUMBER() OVER (ORDER BY embedding <=> $1) AS rank
-			FROM document_chunks
-			WHERE project_id = ANY($2)
-			ORDER BY embedding <=> $1
-			LIMIT $3
-		),
-		fts_ranked AS (
-			SELECT id, content, ROW_NUMBER() OVER (ORDER BY ts_rank(tsv, plainto_tsquery('english', $4)) DESC) AS rank
-			FROM document_chunks
-			WHERE project_id = ANY($2) AND tsv @@ plainto_tsquery('english', $4)
-			LIMIT $3
-		)
-		SELECT COALESCE(v.content, f.content) AS content
-		FROM vector_ranked v
-		FULL OUTER JOIN fts_ranked f ON v.id = f.id
-		ORDER BY
-			COALESCE(1.0 / (60 + v.rank), 0) + COALESCE(1.0 / (60 + f.rank), 0) DESC
-		LIMIT $3`
-		args = []interface{}{vec, projectIDs, limit, query}
-	} else {
-		sql = `
+	var chunks []ChunkText
+	for rows.Next() {
+		var c ChunkText
+		if err := rows.Scan(&c.ID, &c.Content); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// UpdateFTS rebuilds the tsvector of each chunk in ids from the matching
+// entry of searchTexts with the text search configuration ftsLanguage.
+func (r *ChunkRepo) UpdateFTS(ctx context.Context, ftsLanguage string, ids, searchTexts []string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE document_chunks c SET tsv = to_tsvector($1::regconfig, u.search_text), fts_config = $1
+		FROM unnest($2::uuid[], $3::text[]) AS u(id, search_text)
+		WHERE c.id = u.id`, ftsLanguage, ids, searchTexts)
+	if err != nil {
+		return fmt.Errorf("update fts: %w", err)
+	}
+	return nil
+}
+
+// SearchParams tune a hybrid search over one project.
+type SearchParams struct {
+	Limit        int
+	RRFK         int     // Reciprocal Rank Fusion constant
+	VectorWeight float64 // weight of the vector ranking in the fused score
+	FTSWeight    float64 // weight of the full-text ranking in the fused score
+	Language     string  // text search configuration the project was indexed with
+	Filter       models.SearchFilter
+	// ExactTerms are matched literally (case-sensitive) against chunk
+	// content; chunks containing more of them rank higher in a third list
+	// fused with weight ExactWeight.
+	ExactTerms  []string
+	ExactWeight float64
+}
+
+// HybridSearch combines vector similarity, full-text search and exact term
+// matches over one project using weighted Reciprocal Rank Fusion (RRF). Only
+// vectors produced by embeddingModel take part in the vector ranking;
+// Similarity is the cosine similarity of those vectors to the query. query is
+// matched against the full-text index as is, so it should be prepared with
+// rag.SearchQuery. All rankings only consider chunks matching p.Filter.
+func (r *ChunkRepo) HybridSearch(ctx context.Context, embedding []float32, embeddingModel, query, projectID string, p SearchParams) ([]models.ChunkSearchResult, error) {
+	vec := pgvector.NewVector(embedding)
+	args := []interface{}{vec, p.Limit, query, embeddingModel, projectID, p.RRFK, p.VectorWeight, p.FTSWeight, p.Language}
+	filter := filterPredicates(p.Filter, &args)
+	exact := exactRanking(p, filter, &args)
+	args = append(args, p.ExactWeight)
+	exactWeight := fmt.Sprintf("$%d", len(args))
+
+	sql := `
 		WITH vector_ranked AS (
-			SELECT id, content, ROW_NUMBER() OVER (ORDER BY embedding <=> $1) AS rank
+			SELECT id, ROW_NUMBER() OVER (ORDER BY embedding <=> $1) AS rank
 			FROM document_chunks
+			WHERE project_id = $5 AND embedding_model = $4` + filter + `
 			ORDER BY embedding <=> $1
 			LIMIT $2
 		),
 		fts_ranked AS (
-			SELECT id, content, ROW_NUMBER() OVER (ORDER BY ts_rank(tsv, plainto_tsquery('english', $3)) DESC) AS rank
+			SELECT id, ROW_NUMBER() OVER (ORDER BY ts_rank(tsv, plainto_tsquery($9::regconfig, $3)) DESC) AS rank
 			FROM document_chunks
-			WHERE tsv @@ plainto_tsquery('english', $3)
+			WHERE project_id = $5 AND tsv @@ plainto_tsquery($9::regconfig, $3)` + filter + `
+			ORDER BY rank
 			LIMIT $2
+		),
+		exact_ranked AS (` + exact + `),
+		fused AS (
+			SELECT id, MIN(vector_rank) AS vector_rank, MIN(fts_rank) AS fts_rank, MIN(exact_rank) AS exact_rank,
+				SUM(score) AS score
+			FROM (
+				SELECT id, rank AS vector_rank, NULL::bigint AS fts_rank, NULL::bigint AS exact_rank,
+					$7::float8 / ($6 + rank) AS score
+				FROM vector_ranked
+				UNION ALL
+				SELECT id, NULL, rank, NULL, $8::float8 / ($6 + rank) FROM fts_ranked
+				UNION ALL
+				SELECT id, NULL, NULL, rank, ` + exactWeight + `::float8 / ($6 + rank) FROM exact_ranked
+			) ranks
+			GROUP BY id
 		)
-		SELECT COALESCE(v.content, f.content) AS content
-		FROM vector_ranked v
-		FULL OUTER JOIN fts_ranked f ON v.id = f.id
-		ORDER BY
-			COALESCE(1.0 / (60 + v.rank), 0) + COALESCE(1.0 / (60 + f.rank), 0) DESC
+		SELECT c.id, c.chunk_type, c.project_id, c.file_id, COALESCE(c.file_path, c.file_name, ''), COALESCE(c.file_name, ''),
+			c.content, COALESCE(c.symbol_name, ''), COALESCE(c.symbol_kind, ''),
+			COALESCE(c.start_line, 0), COALESCE(c.end_line, 0), c.ordinal, COALESCE(c.start_byte, 0), COALESCE(c.end_byte, 0),
+			fu.vector_rank, fu.fts_rank, fu.exact_rank, CASE WHEN c.embedding_model = $4 THEN 1 - (c.embedding <=> $1) END, fu.score::float8
+		FROM fused fu
+		JOIN document_chunks c ON c.id = fu.id
+		ORDER BY fu.score DESC
 		LIMIT $2`
-		args = []interface{}{vec, limit, query}
-	}
 
 	rows, err := r.db.Query(ctx, sql, args...)
 	if err != nil {
@@ -84,15 +192,438 @@ func (r *ChunkRepo) HybridSearch(ctx context.Context, embedding []float32, query
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []models.ChunkSearchResult
+	for rows.Next() {
+		var res models.ChunkSearchResult
+		if err := rows.Scan(&res.ChunkID, &res.ChunkType, &res.ProjectID, &res.FileID, &res.FilePath, &res.FileName,
+			&res.Content, &res.SymbolName, &res.SymbolKind, &res.StartLine, &res.EndLine, &res.Ordinal, &res.StartByte, &res.EndByte, &res.VectorRank, &res.FTSRank, &res.ExactRank, &res.Similarity, &res.Score); err != nil {
+			return nil, err
+		}
+		if res.Similarity != nil {
+			distance := 1 - *res.Similarity
+			res.VectorDistance = &distance
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// exactRanking returns the query ranking chunks by how many of p.ExactTerms
+// they contain, appending its parameters to args. The LIKE conditions let
+// the trigram index find candidates.
+func exactRanking(p SearchParams, filter string, args *[]interface{}) string {
+	if len(p.ExactTerms) == 0 || p.ExactWeight <= 0 {
+		return `SELECT NULL::uuid AS id, NULL::bigint AS rank WHERE false`
+	}
+	param := func(v interface{}) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	likes := make([]string, len(p.ExactTerms))
+	for i, t := range p.ExactTerms {
+		likes[i] = "content LIKE " + param("%"+escapeLike(t)+"%")
+	}
+	terms := param(p.ExactTerms)
+	return `
+			SELECT id, ROW_NUMBER() OVER (ORDER BY hits DESC, length(content)) AS rank
+			FROM (
+				SELECT id, content,
+					(SELECT count(*) FROM unnest(` + terms + `::text[]) AS t(term) WHERE strpos(content, t.term) > 0) AS hits
+				FROM document_chunks
+				WHERE project_id = $5 AND (` + strings.Join(likes, " OR ") + `)` + filter + `
+			) matched
+			ORDER BY rank
+			LIMIT $2
+		`
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// filterPredicates turns f into SQL conditions on document_chunks, each
+// starting with " AND ", appending their parameters to args.
+func filterPredicates(f models.SearchFilter, args *[]interface{}) string {
+	var b strings.Builder
+	param := func(v interface{}) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	path := "COALESCE(file_path, file_name, '')"
+	if len(f.Paths) > 0 {
+		fmt.Fprintf(&b, " AND %s ~ ANY(%s::text[])", path, param(globPatterns(f.Paths)))
+	}
+	if len(f.ExcludePaths) > 0 {
+		fmt.Fprintf(&b, " AND NOT (%s ~ ANY(%s::text[]))", path, param(globPatterns(f.ExcludePaths)))
+	}
+	if len(f.Exts) > 0 {
+		fmt.Fprintf(&b, " AND lower(file_ext) = ANY(%s::text[])", param(normalizeExts(f.Exts)))
+	}
+	if len(f.ExcludeExts) > 0 {
+		fmt.Fprintf(&b, " AND lower(COALESCE(file_ext, '')) <> ALL(%s::text[])", param(normalizeExts(f.ExcludeExts)))
+	}
+	if len(f.FileIDs) > 0 {
+		fmt.Fprintf(&b, " AND file_id = ANY(%s::uuid[])", param(f.FileIDs))
+	}
+	return b.String()
+}
+
+// globPatterns converts path globs to regular expressions. "**" crosses
+// directories, "*" and "?" do not. Globs with both a slash and a wildcard
+// match from the project root; others ("*.go", "services/api") match at any
+// depth, unless they start with a slash. A glob without wildcards also
+// matches everything below it as a directory.
+func globPatterns(globs []string) []string {
+	patterns := make([]string, len(globs))
+	for i, g := range globs {
+		anchored := strings.HasPrefix(g, "/")
+		g = strings.Trim(g, "/")
+		literal := !strings.ContainsAny(g, "*?")
+
+		var b strings.Builder
+		if anchored || (strings.Contains(g, "/") && !literal) {
+			b.WriteString("^")
+		} else {
+			b.WriteString("(^|/)")
+		}
+		for j := 0; j < len(g); j++ {
+			switch {
+			case strings.HasPrefix(g[j:], "**/"):
+				b.WriteString("(.*/)?")
+				j += 2
+			case strings.HasPrefix(g[j:], "**"):
+				b.WriteString(".*")
+				j++
+			case g[j] == '*':
+				b.WriteString("[^/]*")
+			case g[j] == '?':
+				b.WriteString("[^/]")
+			default:
+				b.WriteString(regexp.QuoteMeta(g[j : j+1]))
+			}
+		}
+		if literal {
+			b.WriteString("(/|$)")
+		} else {
+			b.WriteString("$")
+		}
+		patterns[i] = b.String()
+	}
+	return patterns
+}
+
+// normalizeExts lowercases extensions and gives them the leading dot
+// file_ext is stored with.
+func normalizeExts(exts []string) []string {
+	out := make([]string, len(exts))
+	for i, e := range exts {
+		out[i] = "." + strings.ToLower(strings.TrimPrefix(e, "."))
+	}
+	return out
+}
+
+// GetEmbeddings returns the stored vectors of the given chunks that were
+// produced by embeddingModel, keyed by chunk ID.
+func (r *ChunkRepo) GetEmbeddings(ctx context.Context, ids []string, embeddingModel string) (map[string][]float32, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, embedding::text FROM document_chunks WHERE id = ANY($1::uuid[]) AND embedding_model = $2`,
+		ids, embeddingModel,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string][]float32, len(ids))
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, err
+		}
+		var vec pgvector.Vector
+		if err := vec.Parse(text); err != nil {
+			return nil, fmt.Errorf("parse embedding: %w", err)
+		}
+		result[id] = vec.Slice()
+	}
+	return result, rows.Err()
+}
+
+// GrepParams select chunks for a literal or regular expression search.
+type GrepParams struct {
+	Pattern    string
+	Regex      bool // Pattern is a POSIX regular expression rather than a substring
+	IgnoreCase bool
+	Filter     models.SearchFilter
+	Limit      int
+}
+
+// Grep returns up to p.Limit chunks of a project containing p.Pattern,
+// ordered by file path and position in the file.
+func (r *ChunkRepo) Grep(ctx context.Context, projectID string, p GrepParams) ([]models.ChunkSearchResult, error) {
+	args := []interface{}{projectID, p.Limit}
+	var match string
+	switch {
+	case p.Regex && p.IgnoreCase:
+		match = "content ~* $3"
+	case p.Regex:
+		match = "content ~ $3"
+	case p.IgnoreCase:
+		match = "content ILIKE $3"
+	default:
+		match = "content LIKE $3"
+	}
+	if p.Regex {
+		args = append(args, p.Pattern)
+	} else {
+		args = append(args, "%"+escapeLike(p.Pattern)+"%")
+	}
+	filter := filterPredicates(p.Filter, &args)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, chunk_type, project_id, file_id, COALESCE(file_path, file_name, ''), COALESCE(file_name, ''),
+			content, COALESCE(start_line, 0), COALESCE(end_line, 0), ordinal
+		FROM document_chunks
+		WHERE project_id = $1 AND chunk_type = 'content' AND `+match+filter+`
+		ORDER BY COALESCE(file_path, file_name, ''), ordinal, start_line
+		LIMIT $2`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("grep: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.ChunkSearchResult
+	for rows.Next() {
+		var res models.ChunkSearchResult
+		if err := rows.Scan(&res.ChunkID, &res.ChunkType, &res.ProjectID, &res.FileID, &res.FilePath, &res.FileName,
+			&res.Content, &res.StartLine, &res.EndLine, &res.Ordinal); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// NeighborWindow selects the chunks of a file whose ordinal lies in From..To.
+type NeighborWindow struct {
+	FileID string
+	From   int
+	To     int
+}
+
+// ListWindows returns the chunks covered by the given windows, ordered by
+// file and ordinal.
+func (r *ChunkRepo) ListWindows(ctx context.Context, windows []NeighborWindow) ([]models.ChunkSearchResult, error) {
+	fileIDs := make([]string, len(windows))
+	froms := make([]int32, len(windows))
+	tos := make([]int32, len(windows))
+	for i, w := range windows {
+		fileIDs[i], froms[i], tos[i] = w.FileID, int32(w.From), int32(w.To)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT c.id, c.chunk_type, c.project_id, c.file_id, COALESCE(c.file_path, c.file_name, ''), COALESCE(c.file_name, ''),
+			c.content, COALESCE(c.symbol_name, ''), COALESCE(c.symbol_kind, ''),
+			COALESCE(c.start_line, 0), COALESCE(c.end_line, 0), c.ordinal, COALESCE(c.start_byte, 0), COALESCE(c.end_byte, 0)
+		FROM document_chunks c
+		JOIN unnest($1::uuid[], $2::int[], $3::int[]) AS w(file_id, lo, hi)
+			ON c.file_id = w.file_id AND c.ordinal BETWEEN w.lo AND w.hi
+		ORDER BY c.file_id, c.ordinal`, fileIDs, froms, tos)
+	if err != nil {
+		return nil, fmt.Errorf("list windows: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.ChunkSearchResult
+	for rows.Next() {
+		var res models.ChunkSearchResult
+		if err := rows.Scan(&res.ChunkID, &res.ChunkType, &res.ProjectID, &res.FileID, &res.FilePath, &res.FileName,
+			&res.Content, &res.SymbolName, &res.SymbolKind, &res.StartLine, &res.EndLine, &res.Ordinal, &res.StartByte, &res.EndByte); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// LineRange selects the chunks of a file overlapping lines From..To.
+type LineRange struct {
+	FileID string
+	From   int
+	To     int
+}
+
+// ListLineRanges returns the chunks matching filter that overlap the given
+// line ranges, ordered by file and ordinal.
+func (r *ChunkRepo) ListLineRanges(ctx context.Context, ranges []LineRange, filter models.SearchFilter) ([]models.ChunkSearchResult, error) {
+	fileIDs := make([]string, len(ranges))
+	froms := make([]int32, len(ranges))
+	tos := make([]int32, len(ranges))
+	for i, lr := range ranges {
+		fileIDs[i], froms[i], tos[i] = lr.FileID, int32(lr.From), int32(lr.To)
+	}
+	args := []interface{}{fileIDs, froms, tos}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, chunk_type, project_id, file_id, COALESCE(file_path, file_name, ''), COALESCE(file_name, ''),
+			content, COALESCE(symbol_name, ''), COALESCE(symbol_kind, ''),
+			COALESCE(start_line, 0), COALESCE(end_line, 0), ordinal, COALESCE(start_byte, 0), COALESCE(end_byte, 0)
+		FROM document_chunks c
+		WHERE EXISTS (
+			SELECT 1 FROM unnest($1::uuid[], $2::int[], $3::int[]) AS lr(fid, lo, hi)
+			WHERE c.file_id = lr.fid AND c.start_line <= lr.hi AND c.end_line >= lr.lo
+		) AND chunk_type = 'content'`+filterPredicates(filter, &args)+`
+		ORDER BY file_id, ordinal`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list line ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.ChunkSearchResult
+	for rows.Next() {
+		var res models.ChunkSearchResult
+		if err := rows.Scan(&res.ChunkID, &res.ChunkType, &res.ProjectID, &res.FileID, &res.FilePath, &res.FileName,
+			&res.Content, &res.SymbolName, &res.SymbolKind, &res.StartLine, &res.EndLine, &res.Ordinal, &res.StartByte, &res.EndByte); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// ListIndexedFiles returns the project-relative path of every file of a
+// project that has content chunks, keyed by file ID.
+func (r *ChunkRepo) ListIndexedFiles(ctx context.Context, projectID string) (map[string]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT file_id, COALESCE(file_path, file_name, '')
+		FROM document_chunks
+		WHERE project_id = $1 AND chunk_type = 'content'`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]string)
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, err
+		}
+		files[id] = path
+	}
+	return files, rows.Err()
+}
+
+// ListUnsummarizedFiles returns the path of every file of a project that
+// has content chunks but no file summary, keyed by file ID.
+func (r *ChunkRepo) ListUnsummarizedFiles(ctx context.Context, projectID string) (map[string]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT c.file_id, COALESCE(c.file_path, c.file_name, '')
+		FROM document_chunks c
+		WHERE c.project_id = $1 AND c.chunk_type = 'content'
+			AND NOT EXISTS (
+				SELECT 1 FROM document_chunks s
+				WHERE s.file_id = c.file_id AND s.chunk_type = 'file_summary'
+			)`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list unsummarized files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]string)
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, err
+		}
+		files[id] = path
+	}
+	return files, rows.Err()
+}
+
+// ListSummaries returns the file and directory summaries of a project
+// without their vectors.
+func (r *ChunkRepo) ListSummaries(ctx context.Context, projectID string) ([]models.DocumentChunk, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, file_id, COALESCE(file_path, ''), chunk_type, content, COALESCE(source_hash, ''), COALESCE(embedding_model, '')
+		FROM document_chunks
+		WHERE project_id = $1 AND chunk_type <> 'content'
+		ORDER BY file_path`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.DocumentChunk
+	for rows.Next() {
+		c := models.DocumentChunk{ProjectID: projectID}
+		if err := rows.Scan(&c.ID, &c.FileID, &c.FilePath, &c.ChunkType, &c.Content, &c.SourceHash, &c.EmbeddingModel); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, c)
+	}
+	return summaries, rows.Err()
+}
+
+// ReplaceSummary stores c as the summary of its file or directory, replacing
+// any earlier summary of the same type. Summaries have no ordinal or line
+// range, so neighbor expansion and line lookups pass them by.
+func (r *ChunkRepo) ReplaceSummary(ctx context.Context, c *models.DocumentChunk, ftsLanguage string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM document_chunks WHERE file_id = $1 AND chunk_type = $2`, c.FileID, c.ChunkType); err != nil {
+		return fmt.Errorf("delete old summary: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO document_chunks (id, project_id, file_id, chunk_type, content, content_hash, source_hash,
+			embedding, embedding_model, tsv, fts_config, file_path, file_name, file_ext)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, to_tsvector($10::regconfig, $11), $10, $12, $13, NULLIF($14, ''))`,
+		c.ID, c.ProjectID, c.FileID, c.ChunkType, c.Content, c.ContentHash, c.SourceHash,
+		pgvector.NewVector(c.Embedding), c.EmbeddingModel, ftsLanguage, c.SearchText, c.FilePath, c.FileName, c.FileExt,
+	); err != nil {
+		return fmt.Errorf("insert summary: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteSummary removes the summary of the given type of a file or directory.
+func (r *ChunkRepo) DeleteSummary(ctx context.Context, fileID, chunkType string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM document_chunks WHERE file_id = $1 AND chunk_type = $2`, fileID, chunkType)
+	return err
+}
+
+// ContainingTerms returns which of terms occur verbatim in the content of
+// any of the projects' files.
+func (r *ChunkRepo) ContainingTerms(ctx context.Context, projectIDs, terms []string) (map[string]bool, error) {
+	patterns := make([]string, len(terms))
+	for i, t := range terms {
+		patterns[i] = "%" + escapeLike(t) + "%"
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT t.term FROM unnest($2::text[], $3::text[]) AS t(term, pattern)
+		WHERE EXISTS (
+			SELECT 1 FROM document_chunks
+			WHERE project_id = ANY($1::uuid[]) AND chunk_type = 'content' AND content LIKE t.pattern
+		)`, projectIDs, terms, patterns)
+	if err != nil {
+		return nil, fmt.Errorf("containing terms: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
 	for rows.Next() {
-		var content string
-		if err := rows.Scan(&content); err != nil {
+		var term string
+		if err := rows.Scan(&term); err != nil {
 			return nil, err
 		}
-		results = append(results, content)
+		found[term] = true
 	}
-	return results, nil
+	return found, rows.Err()
 }
 
 func (r *ChunkRepo) DeleteByFileID(ctx context.Context, fileID string) error {
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGlobPatterns(t *testing.T) {
+	tests := []struct {
+		glob    string
+		match   []string
+		noMatch []string
+	}{
+		{"*.go", []string{"main.go", "internal/rag/chunker.go"}, []string{"main.go.orig", "docs/go.md"}},
+		{"services", []string{"services/a.go", "internal/services/b.go", "services"}, []string{"microservices/a.go", "services.go"}},
+		{"/services", []string{"services/a.go"}, []string{"internal/services/b.go"}},
+		{"internal/*.go", []string{"internal/a.go"}, []string{"internal/rag/a.go", "x/internal/a.go"}},
+		{"internal/**/*.go", []string{"internal/a.go", "internal/rag/deep/a.go"}, []string{"cmd/a.go"}},
+		{"**/test?.py", []string{"test1.py", "a/b/testx.py"}, []string{"a/test12.py", "a/test/1.py"}},
+		{"a.b", []string{"a.b", "x/a.b"}, []string{"axb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			re := regexp.MustCompile(globPatterns([]string{tt.glob})[0])
+			for _, p := range tt.match {
+				if !re.MatchString(p) {
+					t.Errorf("%q (%s) does not match %q", tt.glob, re, p)
+				}
+			}
+			for _, p := range tt.noMatch {
+				if re.MatchString(p) {
+					t.Errorf("%q (%s) matches %q", tt.glob, re, p)
+				}
+			}
+		})
+	}
+}
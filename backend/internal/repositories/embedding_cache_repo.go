@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	pgvector "github.com/pgvector/pgvector-go"
+)
+
+type EmbeddingCacheRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewEmbeddingCacheRepo(db *pgxpool.Pool) *EmbeddingCacheRepo {
+	return &EmbeddingCacheRepo{db: db}
+}
+
+// GetMany returns the cached embeddings for the given content hashes, keyed by hash.
+// Hashes without an entry are absent from the result.
+func (r *EmbeddingCacheRepo) GetMany(ctx context.Context, hashes []string) (map[string][]float32, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT content_hash, embedding::text FROM embedding_cache WHERE content_hash = ANY($1)`,
+		hashes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string][]float32, len(hashes))
+	for rows.Next() {
+		var hash, text string
+		if err := rows.Scan(&hash, &text); err != nil {
+			return nil, err
+		}
+		var vec pgvector.Vector
+		if err := vec.Parse(text); err != nil {
+			return nil, fmt.Errorf("parse cached embedding: %w", err)
+		}
+		result[hash] = vec.Slice()
+	}
+	return result, rows.Err()
+}
+
+// PutMany stores embeddings by content hash. Existing entries are kept.
+func (r *EmbeddingCacheRepo) PutMany(ctx context.Context, model string, hashes []string, embeddings [][]float32) error {
+	vectors := make([]string, len(embeddings))
+	for i, e := range embeddings {
+		vectors[i] = pgvector.NewVector(e).String()
+	}
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO embedding_cache (content_hash, model, embedding)
+		 SELECT h, $1, v::vector FROM unnest($2::text[], $3::text[]) AS t(h, v)
+		 ON CONFLICT (content_hash) DO NOTHING`,
+		model, hashes, vectors,
+	)
+	return err
+}
+
+func (r *EmbeddingCacheRepo) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM embedding_cache`).Scan(&n)
+	return n, err
+}
+
+// PruneUnreferenced deletes cache entries whose content hash is not used by
+// any stored chunk and returns how many were removed.
+func (r *EmbeddingCacheRepo) PruneUnreferenced(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM embedding_cache e
+		 WHERE NOT EXISTS (SELECT 1 FROM document_chunks c WHERE c.content_hash = e.content_hash)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rag-chat-system/internal/models"
+)
+
+type ImportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewImportRepo(db *pgxpool.Pool) *ImportRepo {
+	return &ImportRepo{db: db}
+}
+
+// ReplaceByFileID swaps all imports of a file for the given ones in a single
+// transaction.
+func (r *ImportRepo) ReplaceByFileID(ctx context.Context, projectID, fileID, filePath string, imports []models.FileImport) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM file_imports WHERE file_id=$1`, fileID); err != nil {
+		return fmt.Errorf("delete old imports: %w", err)
+	}
+	if len(imports) > 0 {
+		kinds, targets, lines := make([]string, len(imports)), make([]string, len(imports)), make([]int32, len(imports))
+		for i, imp := range imports {
+			kinds[i], targets[i], lines[i] = imp.Kind, imp.Target, int32(imp.Line)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO file_imports (project_id, file_id, file_path, kind, target, line)
+			SELECT $1, $2, $3, kind, target, line
+			FROM unnest($4::text[], $5::text[], $6::int[]) AS i(kind, target, line)`,
+			projectID, fileID, filePath, kinds, targets, lines,
+		); err != nil {
+			return fmt.Errorf("insert imports: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ListByProject returns every import of a project's files, ordered by file
+// path and line.
+func (r *ImportRepo) ListByProject(ctx context.Context, projectID string) ([]models.FileImport, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT file_id, file_path, kind, target, line
+		FROM file_imports
+		WHERE project_id = $1
+		ORDER BY file_path, line`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list imports: %w", err)
+	}
+	defer rows.Close()
+
+	var imports []models.FileImport
+	for rows.Next() {
+		var imp models.FileImport
+		if err := rows.Scan(&imp.FileID, &imp.FilePath, &imp.Kind, &imp.Target, &imp.Line); err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+	return imports, rows.Err()
+}
@@ -18,15 +18,15 @@ func NewMessageRepo(db *pgxpool.Pool) *MessageRepo {
 
 func (r *MessageRepo) Create(ctx context.Context, m *models.Message) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO messages (id, chat_id, role, content) VALUES ($1, $2, $3, $4)`,
-		m.ID, m.ChatID, m.Role, m.Content,
+		`INSERT INTO messages (id, chat_id, role, content, citations, rewritten_query) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))`,
+		m.ID, m.ChatID, m.Role, m.Content, m.Citations, m.RewrittenQuery,
 	)
 	return err
 }
 
 func (r *MessageRepo) ListByChatID(ctx context.Context, chatID string) ([]models.Message, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, chat_id, role, content, created_at FROM messages WHERE chat_id=$1 ORDER BY created_at ASC`,
+		`SELECT id, chat_id, role, content, COALESCE(citations, '[]'::jsonb), COALESCE(rewritten_query, ''), verification, created_at FROM messages WHERE chat_id=$1 ORDER BY created_at ASC`,
 		chatID,
 	)
 	if err != nil {
@@ -37,7 +37,7 @@ func (r *MessageRepo) ListByChatID(ctx context.Context, chatID string) ([]models
 	var messages []models.Message
 	for rows.Next() {
 		var m models.Message
-		if err := rows.Scan(&m.ID, &m.ChatID, &m.Role, &m.Content, &m.CreatedAt); err != nil {
+		if err := rows.Scan(&m.ID, &m.ChatID, &m.Role, &m.Content, &m.Citations, &m.RewrittenQuery, &m.Verification, &m.CreatedAt); err != nil {
 			return nil, err
 		}
 		messages = append(messages, m)
@@ -45,6 +45,12 @@ func (r *MessageRepo) ListByChatID(ctx context.Context, chatID string) ([]models
 	return messages, nil
 }
 
+// UpdateVerification stores the verification result of a message.
+func (r *MessageRepo) UpdateVerification(ctx context.Context, id string, v *models.Verification) error {
+	_, err := r.db.Exec(ctx, `UPDATE messages SET verification=$2 WHERE id=$1`, id, v)
+	return err
+}
+
 func (r *MessageRepo) DeleteByChatID(ctx context.Context, chatID string) error {
 	_, err := r.db.Exec(ctx, `DELETE FROM messages WHERE chat_id=$1`, chatID)
 	return err
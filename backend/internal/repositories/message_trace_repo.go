@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rag-chat-system/internal/models"
+)
+
+type MessageTraceRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMessageTraceRepo(db *pgxpool.Pool) *MessageTraceRepo {
+	return &MessageTraceRepo{db: db}
+}
+
+func (r *MessageTraceRepo) Create(ctx context.Context, t *models.MessageTrace) error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx,
+		`INSERT INTO message_traces (message_id, trace) VALUES ($1, $2)`,
+		t.MessageID, raw,
+	)
+	return err
+}
+
+// GetByMessageID returns the trace of a message, or pgx.ErrNoRows if it has
+// none or it expired.
+func (r *MessageTraceRepo) GetByMessageID(ctx context.Context, messageID string) (*models.MessageTrace, error) {
+	var raw []byte
+	var createdAt time.Time
+	err := r.db.QueryRow(ctx,
+		`SELECT trace, created_at FROM message_traces WHERE message_id=$1`, messageID,
+	).Scan(&raw, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	var t models.MessageTrace
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, err
+	}
+	t.MessageID, t.CreatedAt = messageID, createdAt
+	return &t, nil
+}
+
+// DeleteOlderThan removes traces more than days old and returns how many
+// were deleted.
+func (r *MessageTraceRepo) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM message_traces WHERE created_at < NOW() - make_interval(days => $1)`, days)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"rag-chat-system/internal/models"
@@ -26,7 +29,7 @@ func (r *ProjectRepo) Create(ctx context.Context, p *models.Project) error {
 
 func (r *ProjectRepo) List(ctx context.Context) ([]models.Project, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, name, git_url, git_branch, last_synced_at, created_at FROM projects ORDER BY created_at DESC`,
+		`SELECT id, name, git_url, git_branch, last_synced_at, created_at, settings FROM projects ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -36,9 +39,13 @@ func (r *ProjectRepo) List(ctx context.Context) ([]models.Project, error) {
 	var projects []models.Project
 	for rows.Next() {
 		var p models.Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.GitURL, &p.GitBranch, &p.LastSyncedAt, &p.CreatedAt); err != nil {
+		var raw []byte
+		if err := rows.Scan(&p.ID, &p.Name, &p.GitURL, &p.GitBranch, &p.LastSyncedAt, &p.CreatedAt, &raw); err != nil {
 			return nil, err
 		}
+		if p.Settings, err = decodeSettings(raw); err != nil {
+			return nil, fmt.Errorf("project %s settings: %w", p.ID, err)
+		}
 		projects = append(projects, p)
 	}
 	return projects, nil
@@ -51,12 +58,16 @@ func (r *ProjectRepo) Delete(ctx context.Context, id string) error {
 
 func (r *ProjectRepo) GetByID(ctx context.Context, id string) (*models.Project, error) {
 	var p models.Project
+	var raw []byte
 	err := r.db.QueryRow(ctx,
-		`SELECT id, name, git_url, git_branch, git_token_encrypted, last_synced_at, created_at FROM projects WHERE id=$1`, id,
-	).Scan(&p.ID, &p.Name, &p.GitURL, &p.GitBranch, &p.GitTokenEncrypted, &p.LastSyncedAt, &p.CreatedAt)
+		`SELECT id, name, git_url, git_branch, git_token_encrypted, last_synced_at, created_at, settings FROM projects WHERE id=$1`, id,
+	).Scan(&p.ID, &p.Name, &p.GitURL, &p.GitBranch, &p.GitTokenEncrypted, &p.LastSyncedAt, &p.CreatedAt, &raw)
 	if err != nil {
 		return nil, err
 	}
+	if p.Settings, err = decodeSettings(raw); err != nil {
+		return nil, fmt.Errorf("project %s settings: %w", p.ID, err)
+	}
 	return &p, nil
 }
 
@@ -83,3 +94,94 @@ func (r *ProjectRepo) UpdateLastSyncedAt(ctx context.Context, id string) error {
 	)
 	return err
 }
+
+// GetSettings returns the retrieval settings of a project, with defaults for
+// any key that has not been set.
+func (r *ProjectRepo) GetSettings(ctx context.Context, id string) (models.ProjectSettings, error) {
+	var raw []byte
+	if err := r.db.QueryRow(ctx, `SELECT settings FROM projects WHERE id=$1`, id).Scan(&raw); err != nil {
+		return models.ProjectSettings{}, err
+	}
+	return decodeSettings(raw)
+}
+
+// ListSettings returns the settings of the given projects keyed by project
+// ID, or of every project if ids is empty.
+func (r *ProjectRepo) ListSettings(ctx context.Context, ids []string) (map[string]models.ProjectSettings, error) {
+	query := `SELECT id, settings FROM projects`
+	var args []interface{}
+	if len(ids) > 0 {
+		query += ` WHERE id = ANY($1)`
+		args = append(args, ids)
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]models.ProjectSettings)
+	for rows.Next() {
+		var id string
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		settings, err := decodeSettings(raw)
+		if err != nil {
+			return nil, fmt.Errorf("project %s settings: %w", id, err)
+		}
+		result[id] = settings
+	}
+	return result, rows.Err()
+}
+
+// ResolveIDs returns ids, or the IDs of every project if ids is empty, the
+// same scope ListSettings and retrieval use for an empty selection.
+func (r *ProjectRepo) ResolveIDs(ctx context.Context, ids []string) ([]string, error) {
+	if len(ids) > 0 {
+		return ids, nil
+	}
+	rows, err := r.db.Query(ctx, `SELECT id FROM projects ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		all = append(all, id)
+	}
+	return all, rows.Err()
+}
+
+func (r *ProjectRepo) UpdateSettings(ctx context.Context, id string, settings models.ProjectSettings) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, `UPDATE projects SET settings=$2 WHERE id=$1`, id, raw)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// decodeSettings overlays stored settings on the defaults.
+func decodeSettings(raw []byte) (models.ProjectSettings, error) {
+	settings := models.DefaultProjectSettings()
+	if len(raw) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return models.ProjectSettings{}, err
+	}
+	return settings, nil
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SummaryCacheRepo stores generated summaries by a hash of the model, prompt
+// and input they were generated from.
+type SummaryCacheRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSummaryCacheRepo(db *pgxpool.Pool) *SummaryCacheRepo {
+	return &SummaryCacheRepo{db: db}
+}
+
+// Get returns the cached summary for hash, if any.
+func (r *SummaryCacheRepo) Get(ctx context.Context, hash string) (string, bool, error) {
+	var summary string
+	err := r.db.QueryRow(ctx, `SELECT summary FROM summary_cache WHERE input_hash = $1`, hash).Scan(&summary)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return summary, true, nil
+}
+
+// Put stores a summary by hash. An existing entry is kept.
+func (r *SummaryCacheRepo) Put(ctx context.Context, hash, summary string) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO summary_cache (input_hash, summary) VALUES ($1, $2) ON CONFLICT (input_hash) DO NOTHING`,
+		hash, summary,
+	)
+	return err
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rag-chat-system/internal/models"
+)
+
+type SymbolRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSymbolRepo(db *pgxpool.Pool) *SymbolRepo {
+	return &SymbolRepo{db: db}
+}
+
+// ReplaceByFileID swaps all symbols of a file for the given ones in a single
+// transaction.
+func (r *SymbolRepo) ReplaceByFileID(ctx context.Context, fileID string, symbols []*models.Symbol) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM symbols WHERE file_id=$1`, fileID); err != nil {
+		return fmt.Errorf("delete old symbols: %w", err)
+	}
+	if len(symbols) > 0 {
+		n := len(symbols)
+		ids, projectIDs, paths := make([]string, n), make([]string, n), make([]string, n)
+		names, kinds, containers, signatures := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+		starts, ends := make([]int32, n), make([]int32, n)
+		for i, s := range symbols {
+			ids[i], projectIDs[i], paths[i] = s.ID, s.ProjectID, s.FilePath
+			names[i], kinds[i], containers[i], signatures[i] = s.Name, s.Kind, s.Container, s.Signature
+			starts[i], ends[i] = int32(s.StartLine), int32(s.EndLine)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO symbols (id, project_id, file_id, file_path, name, kind, container, signature, start_line, end_line)
+			SELECT id, project_id, $3, file_path, name, kind, NULLIF(container, ''), signature, start_line, end_line
+			FROM unnest($1::uuid[], $2::uuid[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[], $9::int[], $10::int[])
+				AS s(id, project_id, file_path, name, kind, container, signature, start_line, end_line)`,
+			ids, projectIDs, fileID, paths, names, kinds, containers, signatures, starts, ends,
+		); err != nil {
+			return fmt.Errorf("insert symbols: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Search returns up to limit symbols of a project whose name contains q,
+// ignoring case: exact names first, then prefixes, then shorter names. A
+// dotted query such as "ChunkRepo.Hybrid" also matches the container. An
+// empty q lists the project's symbols in file order.
+func (r *SymbolRepo) Search(ctx context.Context, projectID, q, kind string, limit int) ([]models.Symbol, error) {
+	args := []interface{}{projectID, limit}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := "project_id = $1"
+	order := "file_path, start_line"
+	if q != "" {
+		name := q
+		if i := strings.LastIndex(q, "."); i >= 0 {
+			where += " AND container ILIKE " + param(escapeLike(q[:i]))
+			name = q[i+1:]
+		}
+		exact := param(name)
+		where += " AND name ILIKE " + param("%"+escapeLike(name)+"%")
+		order = fmt.Sprintf("lower(name) = lower(%s) DESC, name ILIKE %s DESC, length(name), ", exact,
+			param(escapeLike(name)+"%")) + order
+	}
+	if kind != "" {
+		where += " AND kind = " + param(kind)
+	}
+
+	return r.query(ctx, `
+		SELECT id, project_id, file_id, file_path, name, kind, COALESCE(container, ''), COALESCE(signature, ''),
+			start_line, end_line
+		FROM symbols
+		WHERE `+where+`
+		ORDER BY `+order+`
+		LIMIT $2`, args...)
+}
+
+// FindDefinitions returns up to limit symbols of the given projects named
+// exactly (case-sensitive) one of names, in the order of names.
+func (r *SymbolRepo) FindDefinitions(ctx context.Context, projectIDs, names []string, limit int) ([]models.Symbol, error) {
+	return r.query(ctx, `
+		SELECT id, project_id, file_id, file_path, name, kind, COALESCE(container, ''), COALESCE(signature, ''),
+			start_line, end_line
+		FROM symbols
+		WHERE project_id = ANY($1::uuid[]) AND name = ANY($2::text[])
+		ORDER BY array_position($2::text[], name), file_path, start_line
+		LIMIT $3`, projectIDs, names, limit)
+}
+
+// ExistingNames returns which of names are declared in any of the projects.
+func (r *SymbolRepo) ExistingNames(ctx context.Context, projectIDs, names []string) (map[string]bool, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT DISTINCT name FROM symbols WHERE project_id = ANY($1::uuid[]) AND name = ANY($2::text[])`,
+		projectIDs, names,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("existing names: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		found[name] = true
+	}
+	return found, rows.Err()
+}
+
+func (r *SymbolRepo) query(ctx context.Context, sql string, args ...interface{}) ([]models.Symbol, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query symbols: %w", err)
+	}
+	defer rows.Close()
+
+	var symbols []models.Symbol
+	for rows.Next() {
+		var s models.Symbol
+		if err := rows.Scan(&s.ID, &s.ProjectID, &s.FileID, &s.FilePath, &s.Name, &s.Kind, &s.Container, &s.Signature,
+			&s.StartLine, &s.EndLine); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, s)
+	}
+	return symbols, rows.Err()
+}
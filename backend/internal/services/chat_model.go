@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ChatMessage is one message of a chat completion request. Role is
+// "system", "user" or "assistant".
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// ChatRequest describes a chat completion. An empty Model selects the
+// provider's default; a nil Temperature leaves it to the provider.
+type ChatRequest struct {
+	Model       string
+	Messages    []ChatMessage
+	Temperature *float32
+}
+
+// ChatStream yields a reply piece by piece. Recv returns io.EOF once the
+// reply is complete.
+type ChatStream interface {
+	Recv() (string, error)
+	Close() error
+}
+
+// ChatModel streams chat completions from a language model provider.
+type ChatModel interface {
+	Stream(ctx context.Context, req ChatRequest) (ChatStream, error)
+}
+
+// Complete runs a request to completion and returns the whole reply.
+func Complete(ctx context.Context, m ChatModel, req ChatRequest) (string, error) {
+	stream, err := m.Stream(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var sb strings.Builder
+	for {
+		token, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(token)
+	}
+}
+
+// OpenAIChatModel streams completions from OpenAI or any server that
+// implements its chat completions API.
+type OpenAIChatModel struct {
+	client       *openai.Client
+	defaultModel string
+}
+
+func NewOpenAIChatModel(client *openai.Client, defaultModel string) *OpenAIChatModel {
+	return &OpenAIChatModel{client: client, defaultModel: defaultModel}
+}
+
+// NewOpenAICompatibleChatModel targets an OpenAI-compatible server at baseURL
+// (e.g. http://localhost:11434/v1 for Ollama).
+func NewOpenAICompatibleChatModel(baseURL, apiKey, defaultModel string, maxRetries int) *OpenAIChatModel {
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = strings.TrimRight(baseURL, "/")
+	cfg.HTTPClient = &http.Client{Transport: newRetryTransport(http.DefaultTransport, maxRetries)}
+	return NewOpenAIChatModel(openai.NewClientWithConfig(cfg), defaultModel)
+}
+
+func (m *OpenAIChatModel) Stream(ctx context.Context, req ChatRequest) (ChatStream, error) {
+	model := req.Model
+	if model == "" {
+		model = m.defaultModel
+	}
+
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{Role: msg.Role, Content: msg.Content}
+	}
+
+	creq := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: messages,
+		Stream:   true,
+	}
+	if req.Temperature != nil {
+		creq.Temperature = *req.Temperature
+		// The client omits a zero temperature; send the smallest non-zero value instead.
+		if creq.Temperature == 0 {
+			creq.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+
+	stream, err := m.client.CreateChatCompletionStream(ctx, creq)
+	if err != nil {
+		return nil, err
+	}
+	return &openAIChatStream{stream: stream}, nil
+}
+
+type openAIChatStream struct {
+	stream *openai.ChatCompletionStream
+}
+
+func (s *openAIChatStream) Recv() (string, error) {
+	for {
+		resp, err := s.stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		// Some chunks (e.g. usage-only) carry no choices or an empty delta.
+		if len(resp.Choices) > 0 && resp.Choices[0].Delta.Content != "" {
+			return resp.Choices[0].Delta.Content, nil
+		}
+	}
+}
+
+func (s *openAIChatStream) Close() error {
+	return s.stream.Close()
+}
+
+// ScriptedChatModel replays canned replies in order, streaming each one word
+// by word. It records every request and is meant for tests and offline runs.
+type ScriptedChatModel struct {
+	mu       sync.Mutex
+	replies  []string
+	requests []ChatRequest
+}
+
+func NewScriptedChatModel(replies ...string) *ScriptedChatModel {
+	return &ScriptedChatModel{replies: replies}
+}
+
+func (m *ScriptedChatModel) Stream(_ context.Context, req ChatRequest) (ChatStream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, req)
+	if len(m.replies) == 0 {
+		return nil, fmt.Errorf("scripted chat model: no reply left for request %d", len(m.requests))
+	}
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+	return &scriptedChatStream{pieces: strings.SplitAfter(reply, " ")}, nil
+}
+
+// Requests returns the requests received so far.
+func (m *ScriptedChatModel) Requests() []ChatRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ChatRequest(nil), m.requests...)
+}
+
+type scriptedChatStream struct {
+	pieces []string
+}
+
+func (s *scriptedChatStream) Recv() (string, error) {
+	if len(s.pieces) == 0 {
+		return "", io.EOF
+	}
+	piece := s.pieces[0]
+	s.pieces = s.pieces[1:]
+	return piece, nil
+}
+
+func (s *scriptedChatStream) Close() error { return nil }
@@ -4,38 +4,101 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	openai "github.com/sashabaranov/go-openai"
 
 	"rag-chat-system/internal/models"
 	"rag-chat-system/internal/rag"
 	"rag-chat-system/internal/repositories"
 )
 
+// repoMapShare is the share of the prompt budget the repo map of structural
+// questions may take, across all of the chat's projects. The map is part of
+// the system prompt, which the assembler does not trim.
+const repoMapShare = 0.25
+
+// StreamEvent is one event emitted while an assistant reply is streamed.
+// Type is "token" for a piece of answer text, "citations" for the list of
+// sources the inline [n] markers refer to or "verification" for the checks
+// of a finished answer in chats that verify their answers.
+type StreamEvent struct {
+	Type         string
+	Token        string
+	Citations    []models.Citation
+	Verification *models.Verification
+}
+
+// ChatOptions are the per-chat generation and retrieval settings accepted when creating or
+// updating a chat. Nil fields keep their current (or default) value.
+type ChatOptions struct {
+	Model             *string  `json:"model"`
+	Temperature       *float32 `json:"temperature"`
+	QueryRewrite      *bool    `json:"query_rewrite"`
+	RetrievalStrategy *string  `json:"retrieval_strategy"`
+	Verify            *bool    `json:"verify"`
+}
+
+func (o ChatOptions) apply(chat *models.Chat) {
+	if o.Model != nil {
+		chat.Model = *o.Model
+	}
+	if o.Temperature != nil {
+		chat.Temperature = o.Temperature
+	}
+	if o.QueryRewrite != nil {
+		chat.QueryRewrite = *o.QueryRewrite
+	}
+	if o.RetrievalStrategy != nil {
+		chat.RetrievalStrategy = *o.RetrievalStrategy
+	}
+	if o.Verify != nil {
+		chat.Verify = *o.Verify
+	}
+}
+
 type ChatService struct {
-	chatRepo    *repositories.ChatRepo
-	messageRepo *repositories.MessageRepo
-	ragService  *RAGService
-	openaiSvc   *OpenAIService
+	chatRepo      *repositories.ChatRepo
+	messageRepo   *repositories.MessageRepo
+	traceRepo     *repositories.MessageTraceRepo
+	ragService    *RAGService
+	summaries     *SummaryService
+	verifier      *Verifier
+	queryRewriter *QueryRewriter
+	chatModel     ChatModel
+	defaultModel  string
+	promptBudget  rag.PromptBudget
 }
 
 func NewChatService(
 	chatRepo *repositories.ChatRepo,
 	messageRepo *repositories.MessageRepo,
+	traceRepo *repositories.MessageTraceRepo,
 	ragService *RAGService,
-	openaiSvc *OpenAIService,
+	summaries *SummaryService,
+	verifier *Verifier,
+	queryRewriter *QueryRewriter,
+	chatModel ChatModel,
+	defaultModel string,
+	promptBudget rag.PromptBudget,
 ) *ChatService {
 	return &ChatService{
-		chatRepo:    chatRepo,
-		messageRepo: messageRepo,
-		ragService:  ragService,
-		openaiSvc:   openaiSvc,
+		chatRepo:      chatRepo,
+		messageRepo:   messageRepo,
+		traceRepo:     traceRepo,
+		ragService:    ragService,
+		summaries:     summaries,
+		verifier:      verifier,
+		queryRewriter: queryRewriter,
+		chatModel:     chatModel,
+		defaultModel:  defaultModel,
+		promptBudget:  promptBudget,
 	}
 }
 
-func (s *ChatService) CreateChat(ctx context.Context, title string, projectIDs []string) (*models.Chat, error) {
+func (s *ChatService) CreateChat(ctx context.Context, title string, projectIDs []string, opts ChatOptions) (*models.Chat, error) {
 	if title == "" {
 		title = "New Chat"
 	}
@@ -43,16 +106,36 @@ func (s *ChatService) CreateChat(ctx context.Context, title string, projectIDs [
 		projectIDs = []string{}
 	}
 	chat := &models.Chat{
-		ID:         uuid.New().String(),
-		Title:      title,
-		ProjectIDs: projectIDs,
+		ID:                uuid.New().String(),
+		Title:             title,
+		ProjectIDs:        projectIDs,
+		Model:             s.defaultModel,
+		QueryRewrite:      true,
+		RetrievalStrategy: models.RetrievalSingle,
 	}
+	opts.apply(chat)
 	if err := s.chatRepo.Create(ctx, chat); err != nil {
 		return nil, err
 	}
 	return chat, nil
 }
 
+// UpdateChat changes a chat's title (if non-nil) and generation settings.
+func (s *ChatService) UpdateChat(ctx context.Context, chatID string, title *string, opts ChatOptions) (*models.Chat, error) {
+	chat, err := s.chatRepo.GetByID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get chat: %w", err)
+	}
+	if title != nil && *title != "" {
+		chat.Title = *title
+	}
+	opts.apply(chat)
+	if err := s.chatRepo.UpdateSettings(ctx, chat); err != nil {
+		return nil, fmt.Errorf("update chat: %w", err)
+	}
+	return chat, nil
+}
+
 func (s *ChatService) ListChats(ctx context.Context) ([]models.Chat, error) {
 	chats, err := s.chatRepo.List(ctx)
 	if err != nil {
@@ -75,6 +158,34 @@ func (s *ChatService) GetMessages(ctx context.Context, chatID string) ([]models.
 	return msgs, nil
 }
 
+// GetMessageTrace returns how an assistant message was produced. It fails
+// with pgx.ErrNoRows for user messages and expired traces.
+func (s *ChatService) GetMessageTrace(ctx context.Context, messageID string) (*models.MessageTrace, error) {
+	return s.traceRepo.GetByMessageID(ctx, messageID)
+}
+
+// StartTraceRetention deletes message traces older than days now and then
+// hourly. It does nothing if days is 0 or less.
+func (s *ChatService) StartTraceRetention(days int) {
+	if days <= 0 {
+		return
+	}
+	prune := func() {
+		n, err := s.traceRepo.DeleteOlderThan(context.Background(), days)
+		if err != nil {
+			log.Printf("[Trace] Retention failed: %v", err)
+		} else if n > 0 {
+			log.Printf("[Trace] Deleted %d traces older than %d days", n, days)
+		}
+	}
+	go func() {
+		prune()
+		for range time.Tick(time.Hour) {
+			prune()
+		}
+	}()
+}
+
 func (s *ChatService) DeleteChat(ctx context.Context, chatID string) error {
 	// Delete messages first (foreign key)
 	if err := s.messageRepo.DeleteByChatID(ctx, chatID); err != nil {
@@ -90,14 +201,33 @@ func (s *ChatService) UpdateProjectIDs(ctx context.Context, chatID string, proje
 	return s.chatRepo.UpdateProjectIDs(ctx, chatID, projectIDs)
 }
 
-func (s *ChatService) SendMessage(ctx context.Context, chatID, userMessage string, projectIDs []string) (<-chan string, <-chan error) {
-	tokenCh := make(chan string, 100)
+// SendMessage answers userMessage from the given projects, streaming the
+// reply. Retrieval is limited to chunks matching filter and any path:, ext:
+// or file: operators in the message, which are left out of the search query.
+func (s *ChatService) SendMessage(ctx context.Context, chatID, userMessage string, projectIDs []string, filter models.SearchFilter) (<-chan StreamEvent, <-chan error) {
+	eventCh := make(chan StreamEvent, 100)
 	errCh := make(chan error, 1)
 
 	go func() {
-		defer close(tokenCh)
+		defer close(eventCh)
 		defer close(errCh)
 
+		chat, err := s.chatRepo.GetByID(ctx, chatID)
+		if err != nil {
+			errCh <- fmt.Errorf("get chat: %w", err)
+			return
+		}
+
+		// Fetch conversation history before saving the new message
+		history, err := s.messageRepo.ListByChatID(ctx, chatID)
+		if err != nil {
+			history = nil
+		}
+
+		// Scope operators narrow the search but are not part of the question
+		question, inline := rag.ParseQueryFilters(userMessage)
+		filter = filter.Merge(inline)
+
 		// Save user message
 		userMsg := &models.Message{
 			ID:      uuid.New().String(),
@@ -105,67 +235,126 @@ func (s *ChatService) SendMessage(ctx context.Context, chatID, userMessage strin
 			Role:    "user",
 			Content: userMessage,
 		}
+
+		// Condense follow-ups into a standalone search query. Messages with
+		// file:line references, such as pasted stack traces, are searched as
+		// written so retrieval can read the referenced lines.
+		searchQuery := question
+		if chat.QueryRewrite && len(history) > 0 && len(rag.ParseFileRefs(question)) == 0 {
+			rewritten, err := s.queryRewriter.Rewrite(ctx, history, question)
+			if err != nil {
+				log.Printf("[Chat] Query rewrite failed, using original message: %v", err)
+			} else {
+				searchQuery = rewritten
+				if rewritten != question {
+					userMsg.RewrittenQuery = rewritten
+				}
+			}
+		}
 		if err := s.messageRepo.Create(ctx, userMsg); err != nil {
 			errCh <- fmt.Errorf("save user message: %w", err)
 			return
 		}
 
+		msgTrace := &models.MessageTrace{
+			Model:          chat.Model,
+			Temperature:    chat.Temperature,
+			SearchQuery:    searchQuery,
+			RewrittenQuery: userMsg.RewrittenQuery,
+		}
+
 		// RAG search
-		chunks, err := s.ragService.SearchRelevantChunks(ctx, userMessage, projectIDs)
-		if err != nil {
+		var chunks []models.ChunkSearchResult
+		template, fallback := rag.SystemPromptWithContext, rag.SystemPromptNoContext
+		if trace, err := s.ragService.Retrieve(ctx, searchQuery, projectIDs, chat.RetrievalStrategy, filter); err == nil {
+			chunks = trace.Chunks
+			msgTrace.Filter = trace.Filter
+			msgTrace.Strategy, msgTrace.Expansions, msgTrace.StrategyErr = trace.Strategy, trace.Expansions, trace.StrategyErr
+			msgTrace.Reranker, msgTrace.RerankErr = trace.Reranker, trace.RerankErr
+			msgTrace.Candidates, msgTrace.Filtered = trace.Candidates, trace.Filtered
+			msgTrace.LowConfidence = trace.LowConfidence
+			msgTrace.References, msgTrace.ReferenceErr = trace.References, trace.ReferenceErr
+			if trace.Candidates > 0 && len(chunks) == 0 {
+				// Everything fell below the relevance thresholds
+				fallback = rag.SystemPromptNoRelevantContext
+			}
+			if trace.LowConfidence {
+				template += rag.LowConfidenceNote
+			}
+		} else {
 			// Non-fatal: proceed without context if search fails
-			chunks = nil
+			log.Printf("[Chat] Retrieval failed: %v", err)
+			msgTrace.RetrievalErr = err.Error()
 		}
 
-		var systemPrompt string
-		if len(chunks) > 0 {
-			ragContext := s.ragService.BuildContext(chunks)
-			systemPrompt = fmt.Sprintf(rag.SystemPromptWithContext, ragContext)
-		} else {
-			systemPrompt = rag.SystemPromptNoContext
+		// Questions about the project's layout get the repo map
+		if rag.IsStructuralQuestion(searchQuery) {
+			repoMap, err := s.summaries.RepoMap(ctx, projectIDs, int(float64(s.promptBudget.Total)*repoMapShare))
+			if err != nil {
+				log.Printf("[Chat] Repo map failed: %v", err)
+			} else if repoMap != "" {
+				section := fmt.Sprintf(rag.RepoMapSection, repoMap)
+				template += strings.ReplaceAll(section, "%", "%%")
+				fallback += section
+				msgTrace.RepoMap = true
+			}
 		}
 
-		// Build messages array: system + history + current user message
-		messages := []openai.ChatCompletionMessage{
-			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+		// Fit context and history into the token budget
+		contextEntries := s.ragService.BuildContext(chunks)
+		prompt := rag.AssemblePrompt(rag.PromptParts{
+			Template: template,
+			Fallback: fallback,
+			Context:  contextEntries,
+			History:  history,
+			Question: userMessage,
+		}, s.promptBudget)
+		log.Printf("[Chat] Prompt tokens: system=%d context=%d (%d/%d chunks) history=%d (%d/%d messages) question=%d total=%d/%d",
+			prompt.Usage.System, prompt.Usage.Context, prompt.ContextUsed, len(chunks),
+			prompt.Usage.History, len(prompt.History), len(history),
+			prompt.Usage.Question, prompt.Usage.Total, prompt.Usage.Budget)
+		msgTrace.SystemPrompt, msgTrace.Usage = prompt.System, prompt.Usage
+		msgTrace.Chunks = traceChunks(chunks, prompt.ContextUsed)
+		msgTrace.HistoryIDs = make([]string, len(prompt.History))
+		for i, msg := range prompt.History {
+			msgTrace.HistoryIDs[i] = msg.ID
 		}
 
-		// Fetch recent conversation history (up to 20 messages)
-		history, err := s.messageRepo.ListByChatID(ctx, chatID)
-		if err == nil && len(history) > 0 {
-			// Exclude the last message (the one we just saved above)
-			if len(history) > 0 && history[len(history)-1].ID == userMsg.ID {
-				history = history[:len(history)-1]
-			}
-			// Keep only the last 20 messages
-			if len(history) > 20 {
-				history = history[len(history)-20:]
-			}
-			for _, msg := range history {
-				role := openai.ChatMessageRoleUser
-				if msg.Role == "assistant" {
-					role = openai.ChatMessageRoleAssistant
-				}
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:    role,
-					Content: msg.Content,
-				})
+		// Only cite the chunks that made it into the prompt
+		var citations []models.Citation
+		if prompt.ContextUsed > 0 {
+			citations = s.ragService.BuildCitations(chunks[:prompt.ContextUsed])
+			eventCh <- StreamEvent{Type: "citations", Citations: citations}
+		}
+
+		// Build messages array: system + history + current user message
+		messages := []ChatMessage{
+			{Role: "system", Content: prompt.System},
+		}
+		for _, msg := range prompt.History {
+			role := "user"
+			if msg.Role == "assistant" {
+				role = "assistant"
 			}
+			messages = append(messages, ChatMessage{
+				Role:    role,
+				Content: msg.Content,
+			})
 		}
 
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleUser,
+		messages = append(messages, ChatMessage{
+			Role:    "user",
 			Content: userMessage,
 		})
 
-		// Stream from OpenAI
-		stream, err := s.openaiSvc.Client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
-			Model:    openai.GPT4oMini,
-			Messages: messages,
-			Stream:   true,
+		// Stream from the chat's model
+		stream, err := s.chatModel.Stream(ctx, ChatRequest{
+			Model:       chat.Model,
+			Messages:    messages,
+			Temperature: chat.Temperature,
 		})
 		if err != nil {
-			errCh <- fmt.Errorf("openai stream: %w", err)
+			errCh <- fmt.Errorf("chat stream: %w", err)
 			return
 		}
 		defer stream.Close()
@@ -173,7 +362,7 @@ func (s *ChatService) SendMessage(ctx context.Context, chatID, userMessage strin
 		var fullResponse strings.Builder
 
 		for {
-			response, err := stream.Recv()
+			token, err := stream.Recv()
 			if err == io.EOF {
 				break
 			}
@@ -182,25 +371,31 @@ func (s *ChatService) SendMessage(ctx context.Context, chatID, userMessage strin
 				return
 			}
 
-			token := response.Choices[0].Delta.Content
 			if token != "" {
 				fullResponse.WriteString(token)
-				tokenCh <- token
+				eventCh <- StreamEvent{Type: "token", Token: token}
 			}
 		}
 
 		// Save assistant message
 		assistantMsg := &models.Message{
-			ID:      uuid.New().String(),
-			ChatID:  chatID,
-			Role:    "assistant",
-			Content: fullResponse.String(),
+			ID:        uuid.New().String(),
+			ChatID:    chatID,
+			Role:      "assistant",
+			Content:   fullResponse.String(),
+			Citations: citations,
 		}
 		if err := s.messageRepo.Create(ctx, assistantMsg); err != nil {
 			errCh <- fmt.Errorf("save assistant message: %w", err)
 			return
 		}
 
+		msgTrace.MessageID = assistantMsg.ID
+		msgTrace.AnswerTokens = rag.TokenCount(assistantMsg.Content)
+		if err := s.traceRepo.Create(ctx, msgTrace); err != nil {
+			log.Printf("[Chat] Saving trace failed: %v", err)
+		}
+
 		// Update chat title from first message (rune-safe truncation)
 		runes := []rune(userMessage)
 		if len(runes) > 50 {
@@ -208,7 +403,43 @@ func (s *ChatService) SendMessage(ctx context.Context, chatID, userMessage strin
 		} else {
 			_ = s.chatRepo.UpdateTitle(ctx, chatID, userMessage)
 		}
+
+		// Check the finished answer against what it was written from
+		if chat.Verify {
+			v, err := s.verifier.Verify(ctx, assistantMsg.Content, contextEntries[:prompt.ContextUsed], projectIDs)
+			if err != nil {
+				log.Printf("[Chat] Verification failed: %v", err)
+				return
+			}
+			if err := s.messageRepo.UpdateVerification(ctx, assistantMsg.ID, v); err != nil {
+				log.Printf("[Chat] Saving verification failed: %v", err)
+			}
+			eventCh <- StreamEvent{Type: "verification", Verification: v}
+		}
 	}()
 
-	return tokenCh, errCh
+	return eventCh, errCh
+}
+
+// traceChunks records the retrieved chunks for a message trace; the first
+// inPrompt of them made it into the prompt.
+func traceChunks(chunks []models.ChunkSearchResult, inPrompt int) []models.TracedChunk {
+	traced := make([]models.TracedChunk, len(chunks))
+	for i, c := range chunks {
+		traced[i] = models.TracedChunk{
+			ChunkID:         c.ChunkID,
+			FilePath:        c.FilePath,
+			Score:           c.Score,
+			Similarity:      c.Similarity,
+			RerankScore:     c.RerankScore,
+			VectorRank:      c.VectorRank,
+			FTSRank:         c.FTSRank,
+			ExactRank:       c.ExactRank,
+			SymbolRank:      c.SymbolRank,
+			LinkedFrom:      c.LinkedFrom,
+			ReferencedLines: c.ReferencedLines,
+			InPrompt:        i < inPrompt,
+		}
+	}
+	return traced
 }
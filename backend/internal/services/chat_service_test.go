@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"rag-chat-system/internal/config"
+	"rag-chat-system/internal/database"
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+	"rag-chat-system/internal/storage"
+)
+
+// testPool connects to TEST_DATABASE_URL and migrates it, or skips the test.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	database.Migrate(pool, config.Load().EmbeddingDimensions, false)
+	return pool
+}
+
+// newTestChatService wires a ChatService like the server does, with the
+// hash embedder and chatModel in place of the network-backed providers.
+func newTestChatService(t *testing.T, pool *pgxpool.Pool, chatModel ChatModel) *ChatService {
+	t.Helper()
+	projectRepo := repositories.NewProjectRepo(pool)
+	fileRepo := repositories.NewFileRepo(pool)
+	chunkRepo := repositories.NewChunkRepo(pool)
+	symbolRepo := repositories.NewSymbolRepo(pool)
+	embeddingSvc := NewEmbeddingService(NewHashEmbedder(config.Load().EmbeddingDimensions), 16, 1)
+	refSvc := NewReferenceService(fileRepo, storage.NewLocalStorage(t.TempDir()))
+	ragSvc := NewRAGService(chunkRepo, symbolRepo, NewGraphService(repositories.NewImportRepo(pool), chunkRepo), refSvc, projectRepo,
+		embeddingSvc, NewQueryExpander(chatModel, "test-model"), NewLexicalReranker(), 20, 5)
+	summarySvc := NewSummaryService(chunkRepo, fileRepo, projectRepo, repositories.NewSummaryCacheRepo(pool),
+		embeddingSvc, NewEmbeddingCacheService(repositories.NewEmbeddingCacheRepo(pool)), chatModel, "test-model")
+	return NewChatService(repositories.NewChatRepo(pool), repositories.NewMessageRepo(pool), repositories.NewMessageTraceRepo(pool),
+		ragSvc, summarySvc, NewVerifier(chatModel, "test-model", fileRepo, chunkRepo, symbolRepo, projectRepo),
+		NewQueryRewriter(chatModel, "test-model"), chatModel, "default-model",
+		rag.PromptBudget{Total: 4000, ContextShare: 0.6})
+}
+
+func TestSendMessageStreamsAndSavesReply(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	// An empty project keeps retrieval away from other data in the database
+	projectRepo := repositories.NewProjectRepo(pool)
+	project := &models.Project{ID: uuid.New().String(), Name: "chat service test"}
+	if err := projectRepo.Create(ctx, project); err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	t.Cleanup(func() { projectRepo.Delete(context.Background(), project.ID) })
+
+	const reply = "Sessions expire after thirty minutes."
+	chatModel := NewScriptedChatModel(reply)
+	svc := newTestChatService(t, pool, chatModel)
+
+	model, temperature, rewrite := "strong-model", float32(0.3), false
+	chat, err := svc.CreateChat(ctx, "", []string{project.ID}, ChatOptions{
+		Model:        &model,
+		Temperature:  &temperature,
+		QueryRewrite: &rewrite,
+	})
+	if err != nil {
+		t.Fatalf("create chat: %v", err)
+	}
+	t.Cleanup(func() { svc.DeleteChat(context.Background(), chat.ID) })
+
+	eventCh, errCh := svc.SendMessage(ctx, chat.ID, "When do sessions expire?", chat.ProjectIDs, models.SearchFilter{})
+	var tokens []string
+	for event := range eventCh {
+		if event.Type == "token" {
+			tokens = append(tokens, event.Token)
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("send message: %v", err)
+	}
+
+	if len(tokens) < 2 {
+		t.Errorf("reply streamed in %d tokens, want one per word", len(tokens))
+	}
+	if got := strings.Join(tokens, ""); got != reply {
+		t.Errorf("streamed %q, want %q", got, reply)
+	}
+
+	msgs, err := svc.GetMessages(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("get messages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Role != "user" || msgs[1].Role != "assistant" {
+		t.Fatalf("saved messages = %+v, want user and assistant", msgs)
+	}
+	if msgs[1].Content != reply {
+		t.Errorf("saved reply %q, want %q", msgs[1].Content, reply)
+	}
+
+	requests := chatModel.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("chat model got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.Model != model {
+		t.Errorf("request model %q, want %q", req.Model, model)
+	}
+	if req.Temperature == nil || *req.Temperature != temperature {
+		t.Errorf("request temperature %v, want %v", req.Temperature, temperature)
+	}
+	if last := req.Messages[len(req.Messages)-1]; last.Role != "user" || last.Content != "When do sessions expire?" {
+		t.Errorf("last request message = %+v, want the question", last)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+)
+
+const (
+	// maxDefinitions bounds how many symbol definitions one query boosts.
+	maxDefinitions = 5
+	// maxChunksPerDefinition bounds the chunks boosted for one definition;
+	// the first ones hold its signature and doc comment.
+	maxChunksPerDefinition = 3
+)
+
+// boostDefinitions ranks the chunks defining symbols named in the query
+// (see rag.QueryIdentifiers) as one more Reciprocal Rank Fusion list,
+// weighted by each project's symbol_weight: chunks already found gain
+// score, the others are added. This lets "what does HybridSearch return"
+// hit the definition even when the question embeds far from its code.
+func (s *RAGService) boostDefinitions(ctx context.Context, chunks []models.ChunkSearchResult, query string, settings map[string]models.ProjectSettings, filter models.SearchFilter) ([]models.ChunkSearchResult, error) {
+	names := rag.QueryIdentifiers(query)
+	if len(names) == 0 {
+		return chunks, nil
+	}
+	var projectIDs []string
+	for id, ps := range settings {
+		if ps.SymbolWeight > 0 {
+			projectIDs = append(projectIDs, id)
+		}
+	}
+	if len(projectIDs) == 0 {
+		return chunks, nil
+	}
+
+	defs, err := s.symbolRepo.FindDefinitions(ctx, projectIDs, names, maxDefinitions)
+	if err != nil || len(defs) == 0 {
+		return chunks, err
+	}
+	ranges := make([]repositories.LineRange, len(defs))
+	for i, d := range defs {
+		ranges[i] = repositories.LineRange{FileID: d.FileID, From: d.StartLine, To: d.EndLine}
+	}
+	found, err := s.chunkRepo.ListLineRanges(ctx, ranges, filter)
+	if err != nil {
+		return nil, err
+	}
+	byFile := make(map[string][]models.ChunkSearchResult)
+	for _, c := range found {
+		byFile[c.FileID] = append(byFile[c.FileID], c)
+	}
+
+	index := make(map[string]int, len(chunks))
+	for i, c := range chunks {
+		index[c.ChunkID] = i
+	}
+	rank := 0
+	for _, d := range defs {
+		taken := 0
+		for _, c := range byFile[d.FileID] {
+			if taken == maxChunksPerDefinition {
+				break
+			}
+			if c.StartLine > d.EndLine || c.EndLine < d.StartLine {
+				continue
+			}
+			taken++
+			if i, ok := index[c.ChunkID]; ok && chunks[i].SymbolRank != nil {
+				continue // also part of an earlier definition
+			}
+			rank++
+			r := rank
+			ps := settingsFor(settings, c.ProjectID)
+			score := ps.SymbolWeight / float64(ps.RRFK+r)
+			if i, ok := index[c.ChunkID]; ok {
+				chunks[i].Score += score
+				chunks[i].SymbolRank = &r
+				continue
+			}
+			c.Score, c.SymbolRank = score, &r
+			index[c.ChunkID] = len(chunks)
+			chunks = append(chunks, c)
+		}
+	}
+
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].Score > chunks[j].Score })
+	return chunks, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"rag-chat-system/internal/models"
+)
+
+// diversifies reports whether any of the projects asks for diversified
+// results rather than the plain ranked order.
+func diversifies(settings map[string]models.ProjectSettings) bool {
+	for _, ps := range settings {
+		if ps.MMRLambda < 1 || ps.MaxChunksPerFile > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// diversify picks k chunks from the ranked candidates by Maximal Marginal
+// Relevance: each pick maximizes lambda*relevance - (1-lambda)*redundancy,
+// where relevance is the candidate's normalized score and redundancy its
+// highest cosine similarity to a chunk already picked, using the stored
+// embeddings. Lambda and the per-file cap come from each chunk's project.
+// Chunks without a usable embedding count as dissimilar to everything.
+func (s *RAGService) diversify(ctx context.Context, chunks []models.ChunkSearchResult, settings map[string]models.ProjectSettings, k int) ([]models.ChunkSearchResult, error) {
+	if !diversifies(settings) {
+		if len(chunks) > k {
+			chunks = chunks[:k]
+		}
+		return chunks, nil
+	}
+
+	var embeddings map[string][]float32
+	for _, ps := range settings {
+		if ps.MMRLambda < 1 {
+			ids := make([]string, len(chunks))
+			for i, c := range chunks {
+				ids[i] = c.ChunkID
+			}
+			var err error
+			if embeddings, err = s.chunkRepo.GetEmbeddings(ctx, ids, s.embeddingService.Model()); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	relevance := normalizedRelevance(chunks)
+	taken := make([]bool, len(chunks))
+	perFile := make(map[string]int)
+	var picked []models.ChunkSearchResult
+	for len(picked) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i, c := range chunks {
+			ps := settingsFor(settings, c.ProjectID)
+			if taken[i] || (ps.MaxChunksPerFile > 0 && perFile[c.FileID] >= ps.MaxChunksPerFile) {
+				continue
+			}
+			redundancy := 0.0
+			if ps.MMRLambda < 1 {
+				for _, p := range picked {
+					redundancy = max(redundancy, cosine(embeddings[c.ChunkID], embeddings[p.ChunkID]))
+				}
+			}
+			if mmr := ps.MMRLambda*relevance[i] - (1-ps.MMRLambda)*redundancy; mmr > bestScore {
+				best, bestScore = i, mmr
+			}
+		}
+		if best < 0 {
+			break
+		}
+		taken[best] = true
+		perFile[chunks[best].FileID]++
+		picked = append(picked, chunks[best])
+	}
+	return picked, nil
+}
+
+// normalizedRelevance scales the candidates' rerank scores (or fused scores
+// when they were not reranked) to 0..1, best first.
+func normalizedRelevance(chunks []models.ChunkSearchResult) []float64 {
+	rel := make([]float64, len(chunks))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, c := range chunks {
+		rel[i] = c.Score
+		if c.RerankScore != nil {
+			rel[i] = *c.RerankScore
+		}
+		lo, hi = min(lo, rel[i]), max(hi, rel[i])
+	}
+	for i := range rel {
+		if hi > lo {
+			rel[i] = (rel[i] - lo) / (hi - lo)
+		} else {
+			rel[i] = 1
+		}
+	}
+	return rel
+}
+
+// cosine returns the cosine similarity of a and b, or 0 if either is
+// missing or they differ in length.
+func cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns text into vectors of a fixed dimension.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the model; it is stored with each chunk and keys the cache.
+	Model() string
+	Dimensions() int
+}
+
+// OpenAIEmbedder calls the OpenAI embeddings API or any server that
+// implements it (Ollama, vLLM, LocalAI, ...).
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  string
+	dims   int
+	// sendDimensions asks the API to shorten vectors; only text-embedding-3 models support it.
+	sendDimensions bool
+}
+
+func NewOpenAIEmbedder(client *openai.Client, model string, dims int) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		client:         client,
+		model:          model,
+		dims:           dims,
+		sendDimensions: strings.HasPrefix(model, "text-embedding-3"),
+	}
+}
+
+// NewOpenAICompatibleEmbedder targets an OpenAI-compatible server at baseURL
+// (e.g. http://localhost:11434/v1 for Ollama).
+func NewOpenAICompatibleEmbedder(baseURL, apiKey, model string, dims, maxRetries int) *OpenAIEmbedder {
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = strings.TrimRight(baseURL, "/")
+	cfg.HTTPClient = &http.Client{Transport: newRetryTransport(http.DefaultTransport, maxRetries)}
+	return &OpenAIEmbedder{
+		client: openai.NewClientWithConfig(cfg),
+		model:  model,
+		dims:   dims,
+	}
+}
+
+func (e *OpenAIEmbedder) Model() string   { return e.model }
+func (e *OpenAIEmbedder) Dimensions() int { return e.dims }
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	req := openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	}
+	if e.sendDimensions {
+		req.Dimensions = e.dims
+	}
+	resp, err := e.client.CreateEmbeddings(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d vectors, got %d", len(texts), len(resp.Data))
+	}
+
+	out := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		if len(d.Embedding) != e.dims {
+			return nil, fmt.Errorf("model %s returned %d dimensions, expected %d", e.model, len(d.Embedding), e.dims)
+		}
+		out[d.Index] = d.Embedding
+	}
+	return out, nil
+}
+
+// HashEmbedder is a deterministic, network-free embedder based on feature
+// hashing of words, identifier parts and character trigrams. It captures
+// lexical overlap only, which is enough for offline development and CI.
+type HashEmbedder struct {
+	dims int
+}
+
+func NewHashEmbedder(dims int) *HashEmbedder {
+	return &HashEmbedder{dims: dims}
+}
+
+func (e *HashEmbedder) Model() string   { return fmt.Sprintf("hash-v1-%d", e.dims) }
+func (e *HashEmbedder) Dimensions() int { return e.dims }
+
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = e.embed(t)
+	}
+	return out, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	vec := make([]float32, e.dims)
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		idx := int(sum % uint64(e.dims))
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vec[idx] += weight
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		add("w:"+lower, 1)
+		for _, part := range splitIdentifier(w) {
+			if part != lower {
+				add("w:"+part, 0.5)
+			}
+		}
+		runes := []rune(lower)
+		for j := 0; j+3 <= len(runes); j++ {
+			add("g:"+string(runes[j:j+3]), 0.25)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
+
+// splitIdentifier breaks camelCase and snake_case identifiers into lower-case parts.
+func splitIdentifier(word string) []string {
+	var parts []string
+	var cur []rune
+	runes := []rune(word)
+	flush := func() {
+		if len(cur) > 0 {
+			parts = append(parts, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			flush()
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"rag-chat-system/internal/repositories"
+)
+
+// EmbeddingCacheService avoids re-embedding text that has been embedded
+// before with the same model, keyed by a hash of (model, text).
+type EmbeddingCacheService struct {
+	repo   *repositories.EmbeddingCacheRepo
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func NewEmbeddingCacheService(repo *repositories.EmbeddingCacheRepo) *EmbeddingCacheService {
+	return &EmbeddingCacheService{repo: repo}
+}
+
+// EmbeddingCacheStats reports cache effectiveness since the server started.
+type EmbeddingCacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	Entries int64   `json:"entries"`
+}
+
+// ContentHash identifies a piece of text embedded with a given model.
+func ContentHash(model, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Embed returns embeddings for texts, taking cached vectors where available
+// and calling embed only for the rest. The content hashes are returned too so
+// callers can record which cache entries they reference.
+func (s *EmbeddingCacheService) Embed(ctx context.Context, model string, texts []string,
+	embed func(context.Context, []string) ([][]float32, error)) ([][]float32, []string, error) {
+	hashes := make([]string, len(texts))
+	for i, t := range texts {
+		hashes[i] = ContentHash(model, t)
+	}
+
+	cached, err := s.repo.GetMany(ctx, hashes)
+	if err != nil {
+		// A broken cache must not block ingestion.
+		log.Printf("[EmbeddingCache] lookup failed: %v", err)
+		cached = nil
+	}
+
+	out := make([][]float32, len(texts))
+	var missTexts, missHashes []string
+	var missIdx []int
+	for i, h := range hashes {
+		if e, ok := cached[h]; ok {
+			out[i] = e
+			continue
+		}
+		missIdx = append(missIdx, i)
+		missTexts = append(missTexts, texts[i])
+		missHashes = append(missHashes, h)
+	}
+	s.hits.Add(int64(len(texts) - len(missIdx)))
+	s.misses.Add(int64(len(missIdx)))
+
+	if len(missTexts) > 0 {
+		fresh, err := embed(ctx, missTexts)
+		if err != nil {
+			return nil, nil, err
+		}
+		for j, i := range missIdx {
+			out[i] = fresh[j]
+		}
+		if err := s.repo.PutMany(ctx, model, missHashes, fresh); err != nil {
+			log.Printf("[EmbeddingCache] store failed: %v", err)
+		}
+	}
+
+	return out, hashes, nil
+}
+
+func (s *EmbeddingCacheService) Stats(ctx context.Context) (*EmbeddingCacheStats, error) {
+	entries, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count entries: %w", err)
+	}
+	stats := &EmbeddingCacheStats{
+		Hits:    s.hits.Load(),
+		Misses:  s.misses.Load(),
+		Entries: entries,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats, nil
+}
+
+// Prune removes cache entries that no stored chunk references.
+func (s *EmbeddingCacheService) Prune(ctx context.Context) (int64, error) {
+	return s.repo.PruneUnreferenced(ctx)
+}
@@ -2,25 +2,77 @@ package services
 
 import (
 	"context"
+	"fmt"
 
-	openai "github.com/sashabaranov/go-openai"
+	"golang.org/x/sync/errgroup"
 )
 
+// EmbeddingService batches embedding requests to the configured Embedder.
 type EmbeddingService struct {
-	openai *OpenAIService
+	embedder  Embedder
+	batchSize int
+	// sem bounds the number of embedding requests in flight across all callers.
+	sem chan struct{}
 }
 
-func NewEmbeddingService(openaiSvc *OpenAIService) *EmbeddingService {
-	return &EmbeddingService{openai: openaiSvc}
+func NewEmbeddingService(embedder Embedder, batchSize, workers int) *EmbeddingService {
+	if batchSize <= 0 {
+		batchSize = 64
+	}
+	if workers <= 0 {
+		workers = 4
+	}
+	return &EmbeddingService{
+		embedder:  embedder,
+		batchSize: batchSize,
+		sem:       make(chan struct{}, workers),
+	}
+}
+
+// Model is the embedding model name, recorded on chunks and used to key cached embeddings.
+func (s *EmbeddingService) Model() string {
+	return s.embedder.Model()
+}
+
+func (s *EmbeddingService) Dimensions() int {
+	return s.embedder.Dimensions()
 }
 
 func (s *EmbeddingService) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	resp, err := s.openai.Client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
-		Input: []string{text},
-		Model: openai.SmallEmbedding3,
-	})
+	embeddings, err := s.CreateEmbeddings(ctx, []string{text})
 	if err != nil {
 		return nil, err
 	}
-	return resp.Data[0].Embedding, nil
+	return embeddings[0], nil
+}
+
+// CreateEmbeddings embeds texts in batches, sending up to the configured
+// number of batches concurrently. The result is in the same order as texts.
+func (s *EmbeddingService) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	g, gctx := errgroup.WithContext(ctx)
+
+	for start := 0; start < len(texts); start += s.batchSize {
+		end := min(start+s.batchSize, len(texts))
+		g.Go(func() error {
+			select {
+			case s.sem <- struct{}{}:
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+			defer func() { <-s.sem }()
+
+			vectors, err := s.embedder.Embed(gctx, texts[start:end])
+			if err != nil {
+				return fmt.Errorf("embed batch: %w", err)
+			}
+			copy(out[start:end], vectors)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
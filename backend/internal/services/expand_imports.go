@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"rag-chat-system/internal/models"
+)
+
+const (
+	// maxImportSeeds bounds how many hit files of a project have their
+	// imports followed, best-ranked first.
+	maxImportSeeds = 3
+	// linkedCandidatesPerFile bounds the linked files searched for each file
+	// a project adds, since an imported Go package may have many files.
+	linkedCandidatesPerFile = 5
+)
+
+// expandImports appends, for each project with import_neighbors set, the
+// best chunk of up to that many files linked to its top hits in the
+// dependency graph. Linked files are searched with the query's embedding
+// and match text, under the same filter; files already among the hits are
+// skipped.
+func (s *RAGService) expandImports(ctx context.Context, hits []models.ChunkSearchResult, embedding []float32, match string, settings map[string]models.ProjectSettings, filter models.SearchFilter) ([]models.ChunkSearchResult, error) {
+	projectIDs := make([]string, 0, len(settings))
+	for id, ps := range settings {
+		if ps.ImportNeighbors > 0 {
+			projectIDs = append(projectIDs, id)
+		}
+	}
+	if len(projectIDs) == 0 || len(hits) == 0 {
+		return hits, nil
+	}
+	sort.Strings(projectIDs)
+
+	hitFiles := make(map[string]bool)
+	for _, h := range hits {
+		hitFiles[h.FileID] = true
+	}
+
+	var added []models.ChunkSearchResult
+	for _, projectID := range projectIDs {
+		ps := settings[projectID]
+		graph, err := s.graph.Graph(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		// Imports come before importers: what a file uses usually explains it
+		linked := make(map[string][]string)
+		for _, e := range graph.Edges {
+			linked[e.From] = append(linked[e.From], e.To)
+		}
+		for _, e := range graph.Edges {
+			linked[e.To] = append(linked[e.To], e.From)
+		}
+
+		linkedFrom := make(map[string]string)
+		var candidates []string
+		seeds := make(map[string]bool)
+		for _, h := range hits {
+			if h.ProjectID != projectID || seeds[h.FileID] || len(seeds) == maxImportSeeds {
+				continue
+			}
+			seeds[h.FileID] = true
+			for _, id := range linked[h.FileID] {
+				if hitFiles[id] || linkedFrom[id] != "" || len(candidates) == ps.ImportNeighbors*linkedCandidatesPerFile {
+					continue
+				}
+				if len(filter.FileIDs) > 0 && !slices.Contains(filter.FileIDs, id) {
+					continue
+				}
+				linkedFrom[id] = h.FilePath
+				candidates = append(candidates, id)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		f := filter
+		f.FileIDs = candidates
+		results, err := s.searchProjects(ctx, embedding, match, map[string]models.ProjectSettings{projectID: ps}, f, 2*len(candidates))
+		if err != nil {
+			return nil, err
+		}
+		picked := 0
+		for _, c := range results {
+			if picked == ps.ImportNeighbors {
+				break
+			}
+			if hitFiles[c.FileID] {
+				continue
+			}
+			hitFiles[c.FileID] = true
+			c.LinkedFrom = linkedFrom[c.FileID]
+			added = append(added, c)
+			picked++
+		}
+	}
+	return append(hits, added...), nil
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 
@@ -19,22 +21,37 @@ type FileService struct {
 	fileRepo      *repositories.FileRepo
 	chunkRepo     *repositories.ChunkRepo
 	ingestService *IngestService
+	summaries     *SummaryService
 	storage       storage.Storage
 	// Keep storagePath just in case for specialized operations, or remove it?
 	// It's used for projectDir construction, but projectDir logic needs to change for S3
+
+	mu            sync.Mutex
+	reindexStatus map[string]*ReindexStatus // projectID -> state
+}
+
+// ReindexStatus reports the progress of a project reindex.
+type ReindexStatus struct {
+	Status string `json:"status"` // "reindexing", "done", "error"
+	Files  int    `json:"files"`  // files reindexed so far
+	Failed int    `json:"failed"`
+	Error  string `json:"error,omitempty"`
 }
 
 func NewFileService(
 	fileRepo *repositories.FileRepo,
 	chunkRepo *repositories.ChunkRepo,
 	ingestService *IngestService,
+	summaries *SummaryService,
 	store storage.Storage,
 ) *FileService {
 	return &FileService{
 		fileRepo:      fileRepo,
 		chunkRepo:     chunkRepo,
 		ingestService: ingestService,
+		summaries:     summaries,
 		storage:       store,
+		reindexStatus: make(map[string]*ReindexStatus),
 	}
 }
 
@@ -50,7 +67,7 @@ var textExtensions = map[string]bool{
 	".c": true, ".cpp": true, ".h": true, ".hpp": true, ".cs": true,
 	".php": true, ".swift": true, ".r": true, ".m": true,
 	".dockerfile": true, ".makefile": true, ".gitignore": true,
-	".tf": true, ".hcl": true, ".proto": true, ".prisma": true,
+	".tf": true, ".hcl": true, ".proto": true, ".prisma": true, ".mod": true,
 	".vue": true, ".svelte": true, ".astro": true,
 	".conf": true, ".cfg": true, ".ini": true, ".properties": true,
 }
@@ -105,7 +122,8 @@ func (s *FileService) UploadFile(ctx context.Context, projectID string, parentID
 
 	// Only ingest text files — skip binary files to avoid PostgreSQL UTF8 errors
 	if isTextFile(filename) && !isBinaryContent(content) {
-		if err := s.ingestService.IngestContent(ctx, projectID, fileID, string(content), filename); err != nil {
+		relPath := s.relativePath(ctx, parentID, filename)
+		if err := s.ingestService.IngestContent(ctx, projectID, fileID, string(content), relPath); err != nil {
 			return nil, fmt.Errorf("ingest: %w", err)
 		}
 	}
@@ -113,6 +131,19 @@ func (s *FileService) UploadFile(ctx context.Context, projectID string, parentID
 	return f, nil
 }
 
+// relativePath returns the project-relative path of a file. Directory records
+// store their relative path, so the parent's path is enough to rebuild it.
+func (s *FileService) relativePath(ctx context.Context, parentID *string, filename string) string {
+	if parentID == nil {
+		return filename
+	}
+	parent, err := s.fileRepo.GetByID(ctx, *parentID)
+	if err != nil {
+		return filename
+	}
+	return parent.Path + "/" + filename
+}
+
 func (s *FileService) EnsureDir(ctx context.Context, projectID string, parentID *string, name, relPath string) (*models.File, error) {
 	existing, err := s.fileRepo.FindByProjectAndPath(ctx, projectID, relPath)
 	if err == nil {
@@ -136,6 +167,12 @@ func (s *FileService) EnsureDir(ctx context.Context, projectID string, parentID
 	return dir, nil
 }
 
+// SummarizeAsync brings the file and directory summaries of a project up to
+// date in the background, once a batch of files has been ingested.
+func (s *FileService) SummarizeAsync(projectID string) {
+	s.summaries.SummarizeAsync(projectID)
+}
+
 func (s *FileService) ListByProject(ctx context.Context, projectID string) ([]models.File, error) {
 	files, err := s.fileRepo.ListByProject(ctx, projectID)
 	if err != nil {
@@ -159,7 +196,12 @@ func (s *FileService) DeleteFile(ctx context.Context, fileID string) error {
 		_ = s.storage.Delete(ctx, f.Path)
 	}
 
-	return s.fileRepo.Delete(ctx, fileID)
+	if err := s.fileRepo.Delete(ctx, fileID); err != nil {
+		return err
+	}
+	// Summaries of the directories above depend on the deleted files
+	s.SummarizeAsync(f.ProjectID)
+	return nil
 }
 
 func (s *FileService) deleteRecursive(ctx context.Context, parentID string) error {
@@ -183,6 +225,115 @@ func (s *FileService) deleteRecursive(ctx context.Context, parentID string) erro
 	return nil
 }
 
+// ReindexAsync starts re-chunking and re-embedding every text file of a
+// project in the background, e.g. after its chunking settings changed.
+func (s *FileService) ReindexAsync(projectID string) error {
+	s.mu.Lock()
+	if st, ok := s.reindexStatus[projectID]; ok && st.Status == "reindexing" {
+		s.mu.Unlock()
+		return fmt.Errorf("reindex already in progress")
+	}
+	st := &ReindexStatus{Status: "reindexing"}
+	s.reindexStatus[projectID] = st
+	s.mu.Unlock()
+
+	go func() {
+		err := s.reindex(context.Background(), projectID, st)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != nil {
+			log.Printf("[Reindex] Project %s failed: %v", projectID, err)
+			st.Status = "error"
+			st.Error = err.Error()
+		} else {
+			st.Status = "done"
+		}
+	}()
+	return nil
+}
+
+// GetReindexStatus returns the state of the last reindex of a project, or nil
+// if none has run since startup.
+func (s *FileService) GetReindexStatus(projectID string) *ReindexStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.reindexStatus[projectID]
+	if !ok {
+		return nil
+	}
+	copied := *st
+	return &copied
+}
+
+// reindex re-ingests every text file of a project from storage. Files that
+// fail are logged and counted but do not stop the run.
+func (s *FileService) reindex(ctx context.Context, projectID string, st *ReindexStatus) error {
+	files, err := s.fileRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+	paths := relativePaths(files)
+
+	for _, f := range files {
+		if f.IsDir || !isTextFile(f.Name) {
+			continue
+		}
+		relPath := paths[f.ID]
+
+		err := s.reindexFile(ctx, projectID, f, relPath)
+		s.mu.Lock()
+		if err != nil {
+			log.Printf("[Reindex] Skipping %s: %v", relPath, err)
+			st.Failed++
+		} else {
+			st.Files++
+		}
+		s.mu.Unlock()
+	}
+	s.SummarizeAsync(projectID)
+	return nil
+}
+
+// relativePaths returns the project-relative path of each file, keyed by
+// ID. Directory records store their relative path, so a file's is its
+// parent's path and its name.
+func relativePaths(files []models.File) map[string]string {
+	dirs := make(map[string]string)
+	for _, f := range files {
+		if f.IsDir {
+			dirs[f.ID] = f.Path
+		}
+	}
+	paths := make(map[string]string, len(files))
+	for _, f := range files {
+		switch {
+		case f.IsDir:
+			paths[f.ID] = f.Path
+		case f.ParentID != nil && dirs[*f.ParentID] != "":
+			paths[f.ID] = dirs[*f.ParentID] + "/" + f.Name
+		default:
+			paths[f.ID] = f.Name
+		}
+	}
+	return paths
+}
+
+func (s *FileService) reindexFile(ctx context.Context, projectID string, f models.File, relPath string) error {
+	rc, err := s.storage.Get(ctx, f.Path)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	if isBinaryContent(content) {
+		return nil
+	}
+	return s.ingestService.IngestContent(ctx, projectID, f.ID, string(content), relPath)
+}
+
 func buildTree(files []models.File) []models.File {
 	byID := make(map[string]*models.File)
 	for i := range files {
@@ -286,6 +286,7 @@ func (s *GitService) Sync(ctx context.Context, projectID string) error {
 	}
 
 	log.Printf("[GitSync] Sync complete for project %s", projectID)
+	s.fileService.SummarizeAsync(projectID)
 	return nil
 }
 
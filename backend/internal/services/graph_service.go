@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+)
+
+type GraphService struct {
+	importRepo *repositories.ImportRepo
+	chunkRepo  *repositories.ChunkRepo
+}
+
+func NewGraphService(importRepo *repositories.ImportRepo, chunkRepo *repositories.ChunkRepo) *GraphService {
+	return &GraphService{importRepo: importRepo, chunkRepo: chunkRepo}
+}
+
+// Graph resolves the stored imports of a project against its indexed files.
+// Resolution happens on read, so files uploaded in any order link up once
+// both sides are indexed. Go imports are matched against the module paths
+// of the project's go.mod files; an import of a Go package links to every
+// non-test file of it.
+func (s *GraphService) Graph(ctx context.Context, projectID string) (*models.DependencyGraph, error) {
+	files, err := s.chunkRepo.ListIndexedFiles(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	imports, err := s.importRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	idByPath := make(map[string]string, len(files))
+	for id, p := range files {
+		paths = append(paths, p)
+		idByPath[p] = id
+	}
+	goModules := make(map[string]string)
+	for _, imp := range imports {
+		if imp.Kind == rag.KindGoModule {
+			goModules[imp.FilePath] = imp.Target
+		}
+	}
+	resolver := rag.NewImportResolver(paths, goModules)
+
+	graph := &models.DependencyGraph{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	nodes := make(map[string]*models.GraphNode, len(files))
+	for id, p := range files {
+		nodes[id] = &models.GraphNode{FileID: id, Path: p}
+	}
+	seen := make(map[[2]string]bool)
+	for _, imp := range imports {
+		if imp.Kind == rag.KindGoModule {
+			continue
+		}
+		targets := resolver.Resolve(rag.Import{Kind: imp.Kind, Target: imp.Target, Line: imp.Line})
+		if len(targets) == 0 {
+			graph.Unresolved++
+			continue
+		}
+		for _, t := range targets {
+			to := idByPath[t]
+			key := [2]string{imp.FileID, to}
+			if to == imp.FileID || seen[key] || nodes[imp.FileID] == nil {
+				continue
+			}
+			seen[key] = true
+			graph.Edges = append(graph.Edges, models.GraphEdge{From: imp.FileID, To: to, Line: imp.Line})
+			nodes[imp.FileID].Imports++
+			nodes[to].ImportedBy++
+		}
+	}
+
+	for _, n := range nodes {
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].Path < graph.Nodes[j].Path })
+	return graph, nil
+}
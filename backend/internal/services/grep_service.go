@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/repositories"
+)
+
+// GrepMatch is one matching line of a grep search. Line is 0 when the chunk
+// was indexed without line numbers.
+type GrepMatch struct {
+	FileID   string `json:"file_id"`
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Text     string `json:"text"`
+	ChunkID  string `json:"chunk_id"`
+}
+
+// GrepRequest is a literal or regular expression search over a project.
+type GrepRequest struct {
+	Pattern    string
+	Regex      bool
+	IgnoreCase bool
+	Filter     models.SearchFilter
+	Limit      int // matching lines to return
+}
+
+// GrepResult lists matching lines in file order. Truncated is set when more
+// lines matched than were returned.
+type GrepResult struct {
+	Matches   []GrepMatch `json:"matches"`
+	Truncated bool        `json:"truncated"`
+}
+
+type GrepService struct {
+	chunkRepo *repositories.ChunkRepo
+}
+
+func NewGrepService(chunkRepo *repositories.ChunkRepo) *GrepService {
+	return &GrepService{chunkRepo: chunkRepo}
+}
+
+// ErrInvalidPattern is returned by Grep for a malformed regular expression.
+var ErrInvalidPattern = errors.New("invalid regular expression")
+
+// maxGrepChunks bounds how many chunks one search reads.
+const maxGrepChunks = 2000
+
+// Grep finds the lines of a project's indexed files matching req. The
+// database narrows the search to matching chunks with the trigram index;
+// lines are then matched one by one, so a regular expression spanning lines
+// finds nothing. Lines repeated in overlapping chunks are reported once.
+func (s *GrepService) Grep(ctx context.Context, projectID string, req GrepRequest) (*GrepResult, error) {
+	matchLine, err := lineMatcher(req)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.chunkRepo.Grep(ctx, projectID, repositories.GrepParams{
+		Pattern:    req.Pattern,
+		Regex:      req.Regex,
+		IgnoreCase: req.IgnoreCase,
+		Filter:     req.Filter,
+		Limit:      maxGrepChunks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GrepResult{Matches: []GrepMatch{}, Truncated: len(chunks) == maxGrepChunks}
+	seen := make(map[string]bool)
+	for _, c := range chunks {
+		for i, text := range strings.Split(c.Content, "\n") {
+			if !matchLine(text) {
+				continue
+			}
+			m := GrepMatch{FileID: c.FileID, FilePath: c.FilePath, Text: strings.TrimRight(text, "\r"), ChunkID: c.ChunkID}
+			key := fmt.Sprintf("%s#%d", c.ChunkID, i)
+			if c.StartLine > 0 {
+				m.Line = c.StartLine + i
+				key = fmt.Sprintf("%s:%d", c.FileID, m.Line)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if len(result.Matches) == req.Limit {
+				result.Truncated = true
+				return result, nil
+			}
+			result.Matches = append(result.Matches, m)
+		}
+	}
+	return result, nil
+}
+
+// lineMatcher returns a function reporting whether one line matches req.
+func lineMatcher(req GrepRequest) (func(string) bool, error) {
+	if req.Regex {
+		expr := req.Pattern
+		if req.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if req.IgnoreCase {
+		pattern := strings.ToLower(req.Pattern)
+		return func(line string) bool { return strings.Contains(strings.ToLower(line), pattern) }, nil
+	}
+	return func(line string) bool { return strings.Contains(line, req.Pattern) }, nil
+}
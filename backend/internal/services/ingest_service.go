@@ -3,41 +3,217 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"path/filepath"
+	"sync"
 
 	"github.com/google/uuid"
 
+	"rag-chat-system/internal/models"
 	"rag-chat-system/internal/rag"
 	"rag-chat-system/internal/repositories"
 )
 
 type IngestService struct {
 	chunkRepo        *repositories.ChunkRepo
+	symbolRepo       *repositories.SymbolRepo
+	importRepo       *repositories.ImportRepo
+	projectRepo      *repositories.ProjectRepo
 	embeddingService *EmbeddingService
+	embeddingCache   *EmbeddingCacheService
+
+	mu      sync.Mutex
+	ftsRuns map[string]bool // projects being rebuilt -> whether to run again
 }
 
-func NewIngestService(chunkRepo *repositories.ChunkRepo, embeddingService *EmbeddingService) *IngestService {
+func NewIngestService(
+	chunkRepo *repositories.ChunkRepo,
+	symbolRepo *repositories.SymbolRepo,
+	importRepo *repositories.ImportRepo,
+	projectRepo *repositories.ProjectRepo,
+	embeddingService *EmbeddingService,
+	embeddingCache *EmbeddingCacheService,
+) *IngestService {
 	return &IngestService{
 		chunkRepo:        chunkRepo,
+		symbolRepo:       symbolRepo,
+		importRepo:       importRepo,
+		projectRepo:      projectRepo,
 		embeddingService: embeddingService,
+		embeddingCache:   embeddingCache,
+		ftsRuns:          make(map[string]bool),
 	}
 }
 
-func (s *IngestService) IngestContent(ctx context.Context, projectID, fileID, content, fileName string) error {
+// IngestContent chunks and embeds a file's content. filePath is the file's
+// path relative to the project root and is kept on each chunk for attribution.
+// Chunk size, overlap and chunker come from the project's settings.
+// Embeddings come from the cache when the same text was embedded before; the
+// rest are embedded in batches. All chunks are written in one transaction,
+// followed by the file's declarations for the symbol index and its imports
+// for the dependency graph.
+func (s *IngestService) IngestContent(ctx context.Context, projectID, fileID, content, filePath string) error {
+	settings, err := s.projectRepo.GetSettings(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("project settings: %w", err)
+	}
+
+	fileName := filepath.Base(filePath)
 	fileExt := filepath.Ext(fileName)
-	chunks := rag.ChunkText(content, 500, 100)
+	var chunks []rag.Chunk
+	switch settings.Chunker {
+	case models.ChunkerCode:
+		chunks = rag.ChunkCode(content, fileExt, settings.ChunkSize, settings.ChunkOverlap)
+	case models.ChunkerText:
+		chunks = rag.ChunkTextLines(content, settings.ChunkSize, settings.ChunkOverlap)
+	default:
+		chunks = rag.ChunkFile(content, fileExt, settings.ChunkSize, settings.ChunkOverlap)
+	}
 
-	for _, chunk := range chunks {
-		embedding, err := s.embeddingService.CreateEmbedding(ctx, chunk)
-		if err != nil {
-			return fmt.Errorf("create embedding: %w", err)
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Content
+	}
+	embeddings, hashes, err := s.embeddingCache.Embed(ctx, s.embeddingService.Model(), texts, s.embeddingService.CreateEmbeddings)
+	if err != nil {
+		return fmt.Errorf("create embeddings: %w", err)
+	}
+
+	records := make([]*models.DocumentChunk, len(chunks))
+	for i, chunk := range chunks {
+		records[i] = &models.DocumentChunk{
+			ID:          uuid.New().String(),
+			ProjectID:   projectID,
+			FileID:      fileID,
+			FilePath:    filePath,
+			FileName:    fileName,
+			FileExt:     fileExt,
+			Content:     chunk.Content,
+			ContentHash: hashes[i],
+			SearchText:  rag.SearchText(chunk.Content),
+			SymbolName:  chunk.SymbolName,
+			SymbolKind:  chunk.SymbolKind,
+			StartLine:   chunk.StartLine,
+			EndLine:     chunk.EndLine,
+			Ordinal:     i,
+			StartByte:   chunk.StartByte,
+			EndByte:     chunk.EndByte,
+			Embedding:   embeddings[i],
+
+			EmbeddingModel: s.embeddingService.Model(),
 		}
+	}
+
+	if err := s.chunkRepo.ReplaceByFileID(ctx, fileID, settings.FTSLanguage, records); err != nil {
+		return fmt.Errorf("store chunks: %w", err)
+	}
+
+	found := rag.IndexSymbols(content, fileExt)
+	symbols := make([]*models.Symbol, len(found))
+	for i, sym := range found {
+		symbols[i] = &models.Symbol{
+			ID:        uuid.New().String(),
+			ProjectID: projectID,
+			FileID:    fileID,
+			FilePath:  filePath,
+			Name:      sym.Name,
+			Kind:      sym.Kind,
+			Container: sym.Container,
+			Signature: sym.Signature,
+			StartLine: sym.StartLine,
+			EndLine:   sym.EndLine,
+		}
+	}
+	if err := s.symbolRepo.ReplaceByFileID(ctx, fileID, symbols); err != nil {
+		return fmt.Errorf("store symbols: %w", err)
+	}
+
+	parsed := rag.ParseImports(content, filePath)
+	imports := make([]models.FileImport, len(parsed))
+	for i, imp := range parsed {
+		imports[i] = models.FileImport{FileID: fileID, FilePath: filePath, Kind: imp.Kind, Target: imp.Target, Line: imp.Line}
+	}
+	if err := s.importRepo.ReplaceByFileID(ctx, projectID, fileID, filePath, imports); err != nil {
+		return fmt.Errorf("store imports: %w", err)
+	}
 
-		chunkID := uuid.New().String()
-		if err := s.chunkRepo.Create(ctx, chunkID, projectID, fileID, chunk, embedding, fileName, fileExt); err != nil {
-			return fmt.Errorf("store chunk: %w", err)
+	return nil
+}
+
+// ftsBatchSize is how many chunks RebuildFTS re-tokenizes per round trip.
+const ftsBatchSize = 500
+
+// RebuildFTS brings a project's full-text index up to date with its text
+// search configuration. Only the tsvectors are rebuilt; chunks and
+// embeddings are kept, so this is much cheaper than a reindex.
+func (s *IngestService) RebuildFTS(ctx context.Context, projectID string) error {
+	settings, err := s.projectRepo.GetSettings(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("project settings: %w", err)
+	}
+	total := 0
+	for {
+		stale, err := s.chunkRepo.ListStaleFTS(ctx, projectID, settings.FTSLanguage, ftsBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			break
+		}
+		ids := make([]string, len(stale))
+		texts := make([]string, len(stale))
+		for i, c := range stale {
+			ids[i], texts[i] = c.ID, rag.SearchText(c.Content)
 		}
+		if err := s.chunkRepo.UpdateFTS(ctx, settings.FTSLanguage, ids, texts); err != nil {
+			return err
+		}
+		total += len(stale)
+	}
+	if total > 0 {
+		log.Printf("[FTS] Rebuilt %d chunks of project %s with %q", total, projectID, settings.FTSLanguage)
 	}
+	return nil
+}
 
+// RebuildFTSAsync runs RebuildFTS in the background. If the project is
+// already being rebuilt, it is rebuilt once more afterwards, in case the
+// running pass read settings that have since changed.
+func (s *IngestService) RebuildFTSAsync(projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, running := s.ftsRuns[projectID]; running {
+		s.ftsRuns[projectID] = true
+		return
+	}
+	s.ftsRuns[projectID] = false
+
+	go func() {
+		for {
+			if err := s.RebuildFTS(context.Background(), projectID); err != nil {
+				log.Printf("[FTS] Project %s failed: %v", projectID, err)
+			}
+			s.mu.Lock()
+			if !s.ftsRuns[projectID] {
+				delete(s.ftsRuns, projectID)
+				s.mu.Unlock()
+				return
+			}
+			s.ftsRuns[projectID] = false
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// BackfillFTS starts a rebuild of every project, picking up chunks indexed
+// before identifiers were pre-tokenized or under an older configuration.
+func (s *IngestService) BackfillFTS(ctx context.Context) error {
+	settings, err := s.projectRepo.ListSettings(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("project settings: %w", err)
+	}
+	for projectID := range settings {
+		s.RebuildFTSAsync(projectID)
+	}
 	return nil
 }
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"net/http"
+
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -8,8 +10,12 @@ type OpenAIService struct {
 	Client *openai.Client
 }
 
-func NewOpenAIService(apiKey string) *OpenAIService {
+// NewOpenAIService creates a client whose requests are retried up to
+// maxRetries times on rate limits and server errors.
+func NewOpenAIService(apiKey string, maxRetries int) *OpenAIService {
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.HTTPClient = &http.Client{Transport: newRetryTransport(http.DefaultTransport, maxRetries)}
 	return &OpenAIService{
-		Client: openai.NewClient(apiKey),
+		Client: openai.NewClientWithConfig(cfg),
 	}
 }
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rag-chat-system/internal/rag"
+)
+
+// listMarker matches a bullet or number the model may put before a query.
+var listMarker = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// QueryExpander generates extra search input for the multi-query and HyDE
+// retrieval strategies.
+type QueryExpander struct {
+	chatModel ChatModel
+	model     string
+	variants  int
+}
+
+func NewQueryExpander(chatModel ChatModel, model string) *QueryExpander {
+	return &QueryExpander{
+		chatModel: chatModel,
+		model:     model,
+		variants:  3,
+	}
+}
+
+// Paraphrase returns alternative phrasings of question, excluding the
+// question itself.
+func (e *QueryExpander) Paraphrase(ctx context.Context, question string) ([]string, error) {
+	temperature := float32(0.7)
+	reply, err := Complete(ctx, e.chatModel, ChatRequest{
+		Model: e.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: fmt.Sprintf(rag.MultiQueryPrompt, e.variants)},
+			{Role: "user", Content: question},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("paraphrase query: %w", err)
+	}
+
+	seen := map[string]bool{strings.ToLower(question): true}
+	var queries []string
+	for _, line := range strings.Split(reply, "\n") {
+		q := listMarker.ReplaceAllString(strings.TrimSpace(line), "")
+		q = strings.Trim(q, "\"'`")
+		if q == "" || seen[strings.ToLower(q)] {
+			continue
+		}
+		seen[strings.ToLower(q)] = true
+		queries = append(queries, q)
+		if len(queries) == e.variants {
+			break
+		}
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("paraphrase query: no queries in reply")
+	}
+	return queries, nil
+}
+
+// Hypothetical returns a made-up passage answering question, whose embedding
+// tends to sit closer to real answers than the question's does.
+func (e *QueryExpander) Hypothetical(ctx context.Context, question string) (string, error) {
+	temperature := float32(0.3)
+	reply, err := Complete(ctx, e.chatModel, ChatRequest{
+		Model: e.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: rag.HyDEPrompt},
+			{Role: "user", Content: question},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", fmt.Errorf("hypothetical answer: %w", err)
+	}
+	passage := strings.TrimSpace(reply)
+	if passage == "" {
+		return "", fmt.Errorf("hypothetical answer: empty reply")
+	}
+	return passage, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+)
+
+// QueryRewriter turns a follow-up question into a standalone search query
+// using the recent conversation, so "and where is that called?" retrieves
+// chunks about whatever "that" referred to.
+type QueryRewriter struct {
+	chatModel  ChatModel
+	model      string
+	maxHistory int
+}
+
+func NewQueryRewriter(chatModel ChatModel, model string) *QueryRewriter {
+	return &QueryRewriter{
+		chatModel:  chatModel,
+		model:      model,
+		maxHistory: 6,
+	}
+}
+
+// Rewrite returns a standalone version of question. With no history the
+// question is returned unchanged.
+func (r *QueryRewriter) Rewrite(ctx context.Context, history []models.Message, question string) (string, error) {
+	if len(history) == 0 {
+		return question, nil
+	}
+	if len(history) > r.maxHistory {
+		history = history[len(history)-r.maxHistory:]
+	}
+
+	var conv strings.Builder
+	for _, msg := range history {
+		fmt.Fprintf(&conv, "%s: %s\n", msg.Role, truncateRunes(msg.Content, 1000))
+	}
+
+	temperature := float32(0)
+	reply, err := Complete(ctx, r.chatModel, ChatRequest{
+		Model: r.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: rag.QueryRewritePrompt},
+			{Role: "user", Content: fmt.Sprintf("Conversation:\n%s\nFollow-up question: %s", conv.String(), question)},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", fmt.Errorf("rewrite query: %w", err)
+	}
+
+	rewritten := strings.Trim(strings.TrimSpace(reply), "\"'`")
+	if rewritten == "" {
+		return question, nil
+	}
+	return rewritten, nil
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with "...".
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -2,32 +2,526 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
+	"golang.org/x/sync/errgroup"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
 	"rag-chat-system/internal/repositories"
 )
 
 type RAGService struct {
-	chunkRepo       *repositories.ChunkRepo
+	chunkRepo        *repositories.ChunkRepo
+	symbolRepo       *repositories.SymbolRepo
+	graph            *GraphService
+	references       *ReferenceService
+	projectRepo      *repositories.ProjectRepo
 	embeddingService *EmbeddingService
+	expander         *QueryExpander
+	reranker         Reranker
+	candidates       int
+	topK             int
 }
 
-func NewRAGService(chunkRepo *repositories.ChunkRepo, embeddingService *EmbeddingService) *RAGService {
+// NewRAGService creates the retrieval pipeline. Hybrid search fetches
+// candidates chunks which the reranker (if not nil) cuts down to topK, unless
+// the searched projects set their own top_k. The
+// expander generates queries for the multi_query and hyde strategies.
+// References reads the lines a query points at by file:line.
+func NewRAGService(
+	chunkRepo *repositories.ChunkRepo,
+	symbolRepo *repositories.SymbolRepo,
+	graph *GraphService,
+	references *ReferenceService,
+	projectRepo *repositories.ProjectRepo,
+	embeddingService *EmbeddingService,
+	expander *QueryExpander,
+	reranker Reranker,
+	candidates, topK int,
+) *RAGService {
+	if topK <= 0 {
+		topK = 8
+	}
+	if candidates < topK {
+		candidates = topK
+	}
 	return &RAGService{
-		chunkRepo:       chunkRepo,
+		chunkRepo:        chunkRepo,
+		symbolRepo:       symbolRepo,
+		graph:            graph,
+		references:       references,
+		projectRepo:      projectRepo,
 		embeddingService: embeddingService,
+		expander:         expander,
+		reranker:         reranker,
+		candidates:       candidates,
+		topK:             topK,
+	}
+}
+
+// searchInput is one hybrid search: the text to embed for the vector side
+// and the text to match for the full-text side.
+type searchInput struct {
+	embed string
+	match string
+}
+
+// Retrieve finds the chunks most relevant to query using the given strategy
+// (one of the models.Retrieval* constants), searching only chunks that match
+// filter, and returns them in a trace describing how they were selected.
+// Lines of project files the query references by file:line, as in a pasted
+// stack trace, are read and put first. No projects means all of them.
+func (s *RAGService) Retrieve(ctx context.Context, query string, projectIDs []string, strategy string, filter models.SearchFilter) (*models.RetrievalTrace, error) {
+	settings, err := s.projectRepo.ListSettings(ctx, projectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("project settings: %w", err)
+	}
+	return s.RetrieveWithSettings(ctx, query, settings, strategy, filter)
+}
+
+// RetrieveWithSettings is Retrieve over the projects keyed in settings, using
+// those settings instead of the stored ones. Indexing settings have no
+// effect here; they apply when files are indexed.
+func (s *RAGService) RetrieveWithSettings(ctx context.Context, query string, settings map[string]models.ProjectSettings, strategy string, filter models.SearchFilter) (*models.RetrievalTrace, error) {
+	trace := &models.RetrievalTrace{Query: query}
+	if !filter.Empty() {
+		trace.Filter = &filter
+	}
+	inputs := s.plan(ctx, trace, query, strategy)
+
+	texts := make([]string, len(inputs))
+	for i, in := range inputs {
+		texts[i] = in.embed
+	}
+	embeddings, err := s.embeddingService.CreateEmbeddings(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	topK := s.topKFor(settings)
+	poolSize := topK
+	if s.reranker != nil || diversifies(settings) {
+		poolSize = max(s.candidates, topK)
+	}
+
+	lists := make([][]models.ChunkSearchResult, len(inputs))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, in := range inputs {
+		g.Go(func() error {
+			results, err := s.searchProjects(gctx, embeddings[i], in.match, settings, filter, poolSize)
+			lists[i] = results
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	chunks := lists[0]
+	if len(lists) > 1 {
+		chunks = fuseResults(lists, poolSize)
+	}
+	if chunks, err = s.boostDefinitions(ctx, chunks, query, settings, filter); err != nil {
+		return nil, err
+	}
+	trace.Candidates = len(chunks)
+
+	chunks = applyThresholds(chunks, settings)
+	trace.Filtered = trace.Candidates - len(chunks)
+
+	if s.reranker != nil && len(chunks) > 1 {
+		trace.Reranker = s.reranker.Name()
+		reranked, err := s.reranker.Rerank(ctx, query, chunks)
+		if err != nil {
+			// Non-fatal: keep the fused order
+			trace.RerankErr = err.Error()
+		} else {
+			chunks = reranked
+		}
+	}
+	if chunks, err = s.diversify(ctx, chunks, settings, topK); err != nil {
+		return nil, err
+	}
+	if chunks, err = s.expandImports(ctx, chunks, embeddings[0], inputs[0].match, settings, filter); err != nil {
+		return nil, err
+	}
+	if chunks, err = s.expandNeighbors(ctx, chunks, settings); err != nil {
+		return nil, err
+	}
+	trace.LowConfidence = len(chunks) > 0 && lowConfidence(chunks, settings)
+
+	// Lines the query points at by file:line come before the search hits
+	excerpts, err := s.references.Resolve(ctx, query, settings)
+	if err != nil {
+		// Non-fatal: answer from the search hits alone
+		trace.ReferenceErr = err.Error()
+	}
+	trace.References = len(excerpts)
+	trace.Chunks = withReferences(excerpts, chunks)
+	return trace, nil
+}
+
+// searchProjects runs one hybrid search per project, each with that
+// project's settings, and merges the results by fused score.
+func (s *RAGService) searchProjects(ctx context.Context, embedding []float32, match string, settings map[string]models.ProjectSettings, filter models.SearchFilter, limit int) ([]models.ChunkSearchResult, error) {
+	var mu sync.Mutex
+	var merged []models.ChunkSearchResult
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(4)
+	for projectID, ps := range settings {
+		g.Go(func() error {
+			results, err := s.chunkRepo.HybridSearch(gctx, embedding, s.embeddingService.Model(), rag.SearchQuery(match), projectID, repositories.SearchParams{
+				Limit:        limit,
+				RRFK:         ps.RRFK,
+				VectorWeight: ps.VectorWeight,
+				FTSWeight:    ps.FTSWeight,
+				Language:     ps.FTSLanguage,
+				Filter:       filter,
+				ExactTerms:   rag.ExactTerms(match),
+				ExactWeight:  ps.ExactWeight,
+			})
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			merged = append(merged, results...)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Score != merged[j].Score {
+			return merged[i].Score > merged[j].Score
+		}
+		return merged[i].ChunkID < merged[j].ChunkID
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
 	}
+	return merged, nil
 }
 
-func (s *RAGService) SearchRelevantChunks(ctx context.Context, query string, projectIDs []string) ([]string, error) {
-	embedding, err := s.embeddingService.CreateEmbedding(ctx, query)
+// topKFor returns how many chunks to keep when searching the given
+// projects: the largest top_k among them, or the server default.
+func (s *RAGService) topKFor(settings map[string]models.ProjectSettings) int {
+	topK := 0
+	for _, ps := range settings {
+		topK = max(topK, ps.TopK)
+	}
+	if topK == 0 {
+		topK = s.topK
+	}
+	return topK
+}
+
+// plan decides which searches a strategy runs. If generating expansions
+// fails, the error is recorded and the plain query is used instead.
+func (s *RAGService) plan(ctx context.Context, trace *models.RetrievalTrace, query, strategy string) []searchInput {
+	single := []searchInput{{embed: query, match: query}}
+	trace.Strategy = models.RetrievalSingle
+
+	switch strategy {
+	case models.RetrievalMultiQuery:
+		paraphrases, err := s.expander.Paraphrase(ctx, query)
+		if err != nil {
+			trace.StrategyErr = err.Error()
+			return single
+		}
+		trace.Strategy = strategy
+		trace.Expansions = paraphrases
+		inputs := single
+		for _, p := range paraphrases {
+			inputs = append(inputs, searchInput{embed: p, match: p})
+		}
+		return inputs
+	case models.RetrievalHyDE:
+		passage, err := s.expander.Hypothetical(ctx, query)
+		if err != nil {
+			trace.StrategyErr = err.Error()
+			return single
+		}
+		trace.Strategy = strategy
+		trace.Expansions = []string{passage}
+		// Full-text search stays on the question: a made-up passage would
+		// require its invented identifiers to match.
+		return []searchInput{{embed: passage, match: query}}
+	}
+	return single
+}
+
+// fuseResults merges the results of several searches. Score becomes the
+// mean of a chunk's hybrid scores across the searches, counting those that
+// missed it as 0, so chunks several searches agree on rank first while the
+// score stays on the scale of a single search: min_score and the projects'
+// rrf_k and weights mean the same for every strategy. A chunk keeps its best
+// similarity and any rank that matched it.
+func fuseResults(lists [][]models.ChunkSearchResult, limit int) []models.ChunkSearchResult {
+	n := float64(len(lists))
+	index := make(map[string]int)
+	var merged []models.ChunkSearchResult
+	for _, list := range lists {
+		for _, c := range list {
+			score := c.Score / n
+			i, ok := index[c.ChunkID]
+			if !ok {
+				index[c.ChunkID] = len(merged)
+				c.Score = score
+				merged = append(merged, c)
+				continue
+			}
+			m := &merged[i]
+			m.Score += score
+			if c.Similarity != nil && (m.Similarity == nil || *c.Similarity > *m.Similarity) {
+				m.Similarity, m.VectorDistance = c.Similarity, c.VectorDistance
+			}
+			if m.VectorRank == nil {
+				m.VectorRank = c.VectorRank
+			}
+			if m.FTSRank == nil {
+				m.FTSRank = c.FTSRank
+			}
+			if m.ExactRank == nil {
+				m.ExactRank = c.ExactRank
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// settingsFor returns a project's settings, or the defaults if it has none.
+func settingsFor(settings map[string]models.ProjectSettings, projectID string) models.ProjectSettings {
+	if ps, ok := settings[projectID]; ok {
+		return ps
+	}
+	return models.DefaultProjectSettings()
+}
+
+// expandNeighbors widens each hit with the chunks around it, as many as its
+// project's neighbor_chunks setting asks for. Hits of the same file whose
+// windows overlap or touch become one entry at the position of the
+// best-ranked of them, so the model sees whole functions once instead of
+// fragments several times.
+func (s *RAGService) expandNeighbors(ctx context.Context, hits []models.ChunkSearchResult, settings map[string]models.ProjectSettings) ([]models.ChunkSearchResult, error) {
+	// span is a merged window; leader is the index of its best hit.
+	type span struct {
+		fileID   string
+		from, to int
+		leader   int
+	}
+	var spans []*span
+	spanOf := make(map[int]*span)
+	for i, h := range hits {
+		n := settingsFor(settings, h.ProjectID).NeighborChunks
+		if n <= 0 || h.Ordinal == nil {
+			continue
+		}
+		from, to := max(*h.Ordinal-n, 0), *h.Ordinal+n
+		// Hits are in rank order, so an existing span's leader always ranks higher
+		var merged *span
+		for _, sp := range spans {
+			if sp.fileID == h.FileID && from <= sp.to+1 && to >= sp.from-1 {
+				merged = sp
+				break
+			}
+		}
+		if merged == nil {
+			merged = &span{fileID: h.FileID, from: from, to: to, leader: i}
+			spans = append(spans, merged)
+		} else {
+			merged.from, merged.to = min(merged.from, from), max(merged.to, to)
+		}
+		spanOf[i] = merged
+
+		// A widened span may now reach other spans of the file; absorb them
+		kept := spans[:0]
+		for _, sp := range spans {
+			if sp != merged && sp.fileID == merged.fileID && sp.from <= merged.to+1 && sp.to >= merged.from-1 {
+				merged.from, merged.to = min(merged.from, sp.from), max(merged.to, sp.to)
+				merged.leader = min(merged.leader, sp.leader)
+				for j, other := range spanOf {
+					if other == sp {
+						spanOf[j] = merged
+					}
+				}
+				continue
+			}
+			kept = append(kept, sp)
+		}
+		spans = kept
+	}
+	if len(spans) == 0 {
+		return hits, nil
+	}
+
+	windows := make([]repositories.NeighborWindow, len(spans))
+	for i, sp := range spans {
+		windows[i] = repositories.NeighborWindow{FileID: sp.fileID, From: sp.from, To: sp.to}
+	}
+	chunks, err := s.chunkRepo.ListWindows(ctx, windows)
 	if err != nil {
 		return nil, err
 	}
+	byFile := make(map[string][]models.ChunkSearchResult)
+	for _, c := range chunks {
+		byFile[c.FileID] = append(byFile[c.FileID], c)
+	}
 
-	return s.chunkRepo.HybridSearch(ctx, embedding, query, projectIDs, 10)
+	var result []models.ChunkSearchResult
+	for i, h := range hits {
+		sp, ok := spanOf[i]
+		if !ok {
+			result = append(result, h)
+			continue
+		}
+		if sp.leader != i {
+			continue
+		}
+		var window []models.ChunkSearchResult
+		for _, c := range byFile[sp.fileID] {
+			if *c.Ordinal >= sp.from && *c.Ordinal <= sp.to {
+				window = append(window, c)
+			}
+		}
+		result = append(result, mergeWindow(h, window))
+	}
+	return result, nil
 }
 
-func (s *RAGService) BuildContext(chunks []string) string {
-	return strings.Join(chunks, "\n\n---\n\n")
+// mergeWindow returns hit with its content replaced by the consecutive
+// chunks of window. Byte offsets let overlapping text be kept once; chunks
+// without a usable byte range are joined on a new line instead.
+func mergeWindow(hit models.ChunkSearchResult, window []models.ChunkSearchResult) models.ChunkSearchResult {
+	if len(window) == 0 {
+		return hit
+	}
+	var b strings.Builder
+	end := -1
+	for _, c := range window {
+		exact := c.EndByte-c.StartByte == len(c.Content)
+		switch {
+		case exact && end >= c.StartByte:
+			if c.EndByte > end {
+				b.WriteString(c.Content[end-c.StartByte:])
+			}
+		default:
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString(c.Content)
+		}
+		if exact {
+			end = max(end, c.EndByte)
+		} else {
+			end = -1
+		}
+		if c.ChunkID != hit.ChunkID {
+			hit.NeighborIDs = append(hit.NeighborIDs, c.ChunkID)
+		}
+	}
+
+	first, last := window[0], window[len(window)-1]
+	hit.Content = b.String()
+	hit.StartLine, hit.EndLine = first.StartLine, max(last.EndLine, hit.EndLine)
+	hit.StartByte, hit.EndByte = first.StartByte, last.EndByte
+	if first.SymbolName != hit.SymbolName || last.SymbolName != hit.SymbolName {
+		// The window spans more than the hit's symbol
+		hit.SymbolName, hit.SymbolKind = "", ""
+	}
+	return hit
+}
+
+// applyThresholds drops chunks below their project's relevance thresholds.
+// A full-text, exact or symbol match keeps a chunk even when its similarity
+// is low, since exact identifiers often embed poorly.
+func applyThresholds(chunks []models.ChunkSearchResult, settings map[string]models.ProjectSettings) []models.ChunkSearchResult {
+	kept := chunks[:0]
+	for _, c := range chunks {
+		ps := settingsFor(settings, c.ProjectID)
+		if c.Score < ps.MinScore {
+			continue
+		}
+		if c.FTSRank == nil && c.ExactRank == nil && c.SymbolRank == nil && (c.Similarity == nil || *c.Similarity < ps.MinSimilarity) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// lowConfidence reports whether no chunk reaches its project's
+// low-confidence similarity.
+func lowConfidence(chunks []models.ChunkSearchResult, settings map[string]models.ProjectSettings) bool {
+	for _, c := range chunks {
+		ps := settingsFor(settings, c.ProjectID)
+		if c.Similarity != nil && *c.Similarity >= ps.LowConfidenceSimilarity {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildContext numbers each chunk so the model can cite it inline as [n].
+// Entries are returned separately so the prompt assembler can budget them.
+func (s *RAGService) BuildContext(chunks []models.ChunkSearchResult) []string {
+	parts := make([]string, len(chunks))
+	for i, c := range chunks {
+		parts[i] = fmt.Sprintf("[%d] %s\n%s", i+1, sourceLabel(c), c.Content)
+	}
+	return parts
+}
+
+// sourceLabel describes where a chunk comes from, e.g. "a/b.go:10-42 (method Repo.Get)".
+func sourceLabel(c models.ChunkSearchResult) string {
+	switch c.ChunkType {
+	case models.ChunkTypeFileSummary:
+		return c.FilePath + " (file summary)"
+	case models.ChunkTypeDirSummary:
+		return c.FilePath + "/ (directory summary)"
+	}
+	label := c.FilePath
+	if c.StartLine > 0 {
+		label += fmt.Sprintf(":%d-%d", c.StartLine, c.EndLine)
+	}
+	if c.SymbolName != "" {
+		label += fmt.Sprintf(" (%s %s)", c.SymbolKind, c.SymbolName)
+	}
+	if len(c.ReferencedLines) > 0 {
+		lines := make([]string, len(c.ReferencedLines))
+		for i, l := range c.ReferencedLines {
+			lines[i] = strconv.Itoa(l)
+		}
+		word := "line"
+		if len(lines) > 1 {
+			word = "lines"
+		}
+		label += fmt.Sprintf(" (question references %s %s)", word, strings.Join(lines, ", "))
+	}
+	return label
+}
+
+// BuildCitations maps the markers used by BuildContext back to their source files.
+func (s *RAGService) BuildCitations(chunks []models.ChunkSearchResult) []models.Citation {
+	citations := make([]models.Citation, len(chunks))
+	for i, c := range chunks {
+		citations[i] = models.Citation{
+			Marker:   i + 1,
+			ChunkID:  c.ChunkID,
+			FileID:   c.FileID,
+			FilePath: c.FilePath,
+			FileName: c.FileName,
+		}
+	}
+	return citations
 }
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"rag-chat-system/internal/models"
+)
+
+func TestMergeWindow(t *testing.T) {
+	const text = "line one\nline two\nline three\nline four\n"
+	// chunk cuts text[start:end] on lines from-to
+	chunk := func(id string, start, end, from, to int, symbol string) models.ChunkSearchResult {
+		return models.ChunkSearchResult{ChunkID: id, Content: text[start:end], StartByte: start, EndByte: end,
+			StartLine: from, EndLine: to, SymbolName: symbol}
+	}
+	a := chunk("a", 0, 18, 1, 2, "")
+	b := chunk("b", 9, 29, 2, 3, "Run") // overlaps a by "line two\n"
+	c := chunk("c", 29, 39, 4, 4, "")
+	noRange := models.ChunkSearchResult{ChunkID: "x", Content: "summary", StartLine: 1, EndLine: 1}
+
+	tests := []struct {
+		name      string
+		hit       models.ChunkSearchResult
+		window    []models.ChunkSearchResult
+		content   string
+		lines     [2]int
+		bytes     [2]int
+		neighbors []string
+		symbol    string
+	}{
+		{
+			name: "empty window keeps the hit", hit: b, window: nil,
+			content: b.Content, lines: [2]int{2, 3}, bytes: [2]int{9, 29}, symbol: "Run",
+		},
+		{
+			name: "overlap kept once", hit: b, window: []models.ChunkSearchResult{a, b, c},
+			content: text[:39], lines: [2]int{1, 4}, bytes: [2]int{0, 39}, neighbors: []string{"a", "c"},
+		},
+		{
+			name: "symbol kept inside it", hit: b, window: []models.ChunkSearchResult{b},
+			content: b.Content, lines: [2]int{2, 3}, bytes: [2]int{9, 29}, symbol: "Run",
+		},
+		{
+			name: "gap joined on a new line", hit: a, window: []models.ChunkSearchResult{a, c},
+			content: text[:18] + text[29:39], lines: [2]int{1, 4}, bytes: [2]int{0, 39}, neighbors: []string{"c"},
+		},
+		{
+			name: "no byte range", hit: noRange, window: []models.ChunkSearchResult{noRange, c},
+			content: "summary\n" + c.Content, lines: [2]int{1, 4}, bytes: [2]int{0, 39}, neighbors: []string{"c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeWindow(tt.hit, tt.window)
+			if got.Content != tt.content {
+				t.Errorf("content = %q, want %q", got.Content, tt.content)
+			}
+			if lines := [2]int{got.StartLine, got.EndLine}; lines != tt.lines {
+				t.Errorf("lines = %v, want %v", lines, tt.lines)
+			}
+			if bytes := [2]int{got.StartByte, got.EndByte}; bytes != tt.bytes {
+				t.Errorf("bytes = %v, want %v", bytes, tt.bytes)
+			}
+			if !reflect.DeepEqual(got.NeighborIDs, tt.neighbors) {
+				t.Errorf("neighbors = %v, want %v", got.NeighborIDs, tt.neighbors)
+			}
+			if got.SymbolName != tt.symbol {
+				t.Errorf("symbol = %q, want %q", got.SymbolName, tt.symbol)
+			}
+		})
+	}
+}
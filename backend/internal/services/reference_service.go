@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+	"rag-chat-system/internal/storage"
+)
+
+// maxResolvedRefs bounds the references of one question that are read.
+// Stack traces list the failing frame first, so the earliest ones count.
+const maxResolvedRefs = 5
+
+// ReferenceService reads the lines of project files that a question refers
+// to by file:line, e.g. in a pasted panic or stack trace.
+type ReferenceService struct {
+	fileRepo *repositories.FileRepo
+	storage  storage.Storage
+}
+
+func NewReferenceService(fileRepo *repositories.FileRepo, store storage.Storage) *ReferenceService {
+	return &ReferenceService{fileRepo: fileRepo, storage: store}
+}
+
+// Resolve finds the file:line references in text (see rag.ParseFileRefs),
+// matches them against the files of the projects keyed in settings and
+// returns the referenced lines with each project's ref_window lines around
+// them, one excerpt per file with nearby references merged, in order of
+// first reference. References to files outside the projects, such as
+// standard library frames, are skipped.
+func (s *ReferenceService) Resolve(ctx context.Context, text string, settings map[string]models.ProjectSettings) ([]models.ChunkSearchResult, error) {
+	refs := rag.ParseFileRefs(text)
+	if len(refs) == 0 || len(settings) == 0 {
+		return nil, nil
+	}
+	projectIDs := make([]string, 0, len(settings))
+	for id := range settings {
+		projectIDs = append(projectIDs, id)
+	}
+	sort.Strings(projectIDs)
+
+	type target struct {
+		file   models.File
+		path   string
+		window int
+		lines  []int
+	}
+	var targets []*target
+	byFile := make(map[string]*target)
+	resolved := 0
+	for _, projectID := range projectIDs {
+		window := settings[projectID].RefWindow
+		if window <= 0 {
+			continue
+		}
+		files, err := s.fileRepo.ListByProject(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("list files: %w", err)
+		}
+		relPaths := relativePaths(files)
+		var paths []string
+		byPath := make(map[string]models.File)
+		for _, f := range files {
+			if !f.IsDir && isTextFile(f.Name) {
+				paths = append(paths, relPaths[f.ID])
+				byPath[relPaths[f.ID]] = f
+			}
+		}
+
+		for _, ref := range refs {
+			if resolved == maxResolvedRefs {
+				break
+			}
+			p := rag.MatchRefPath(ref.Path, paths)
+			if p == "" {
+				continue
+			}
+			resolved++
+			f := byPath[p]
+			t, ok := byFile[f.ID]
+			if !ok {
+				t = &target{file: f, path: p, window: window}
+				byFile[f.ID] = t
+				targets = append(targets, t)
+			}
+			t.lines = append(t.lines, ref.Line)
+		}
+	}
+
+	var excerpts []models.ChunkSearchResult
+	for _, t := range targets {
+		found, err := s.excerpts(ctx, t.file, t.path, t.lines, t.window)
+		if err != nil {
+			return nil, err
+		}
+		excerpts = append(excerpts, found...)
+	}
+	return excerpts, nil
+}
+
+// excerpts reads a file and cuts the windows around the given lines from
+// it, merging windows that overlap. Lines past the end of the file, e.g.
+// from a trace of another version, are ignored. Excerpts are not stored
+// chunks; their ChunkID is built from the file ID and line range (see
+// referenceChunkID) so citations and traces can still tell them apart.
+func (s *ReferenceService) excerpts(ctx context.Context, f models.File, relPath string, lines []int, window int) ([]models.ChunkSearchResult, error) {
+	rc, err := s.storage.Get(ctx, f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", relPath, err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", relPath, err)
+	}
+	if isBinaryContent(content) {
+		return nil, nil
+	}
+	fileLines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+
+	sort.Ints(lines)
+	var excerpts []models.ChunkSearchResult
+	for _, line := range lines {
+		if line > len(fileLines) {
+			continue
+		}
+		from, to := max(line-window, 1), min(line+window, len(fileLines))
+		if n := len(excerpts); n > 0 && from <= excerpts[n-1].EndLine+1 {
+			last := &excerpts[n-1]
+			last.EndLine = max(last.EndLine, to)
+			if line != last.ReferencedLines[len(last.ReferencedLines)-1] {
+				last.ReferencedLines = append(last.ReferencedLines, line)
+			}
+			continue
+		}
+		excerpts = append(excerpts, models.ChunkSearchResult{
+			ProjectID:       f.ProjectID,
+			FileID:          f.ID,
+			FilePath:        relPath,
+			FileName:        f.Name,
+			StartLine:       from,
+			EndLine:         to,
+			ReferencedLines: []int{line},
+		})
+	}
+	for i := range excerpts {
+		e := &excerpts[i]
+		e.ChunkID = referenceChunkID(f.ID, e.StartLine, e.EndLine)
+		e.Content = strings.Join(fileLines[e.StartLine-1:e.EndLine], "\n")
+	}
+	return excerpts, nil
+}
+
+// referenceChunkID is the ID of the excerpt of lines from-to of a file,
+// e.g. "ref:<file id>:40-52". It is stable across questions, and cannot
+// collide with a stored chunk's UUID.
+func referenceChunkID(fileID string, from, to int) string {
+	return fmt.Sprintf("ref:%s:%d-%d", fileID, from, to)
+}
+
+// withReferences puts excerpts ahead of hits, dropping hits whose lines an
+// excerpt of the same file already covers.
+func withReferences(excerpts, hits []models.ChunkSearchResult) []models.ChunkSearchResult {
+	if len(excerpts) == 0 {
+		return hits
+	}
+	out := append([]models.ChunkSearchResult{}, excerpts...)
+	for _, h := range hits {
+		covered := false
+		for _, e := range excerpts {
+			if h.FileID == e.FileID && h.StartLine >= e.StartLine && h.EndLine <= e.EndLine && h.StartLine > 0 {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/storage"
+)
+
+func TestReferenceExcerpts(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir())
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := store.Put(ctx, "p/f/main.go", strings.NewReader(strings.Join(lines, "\n")+"\n")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	svc := NewReferenceService(nil, store)
+	f := models.File{ID: "file-1", ProjectID: "project-1", Name: "main.go", Path: "p/f/main.go"}
+
+	// 5 and 8 share a window, 20 has its own and 99 is past the end
+	got, err := svc.excerpts(ctx, f, "cmd/main.go", []int{20, 8, 5, 99}, 2)
+	if err != nil {
+		t.Fatalf("excerpts: %v", err)
+	}
+	want := []struct {
+		id         string
+		start, end int
+		refs       []int
+	}{
+		{"ref:file-1:3-10", 3, 10, []int{5, 8}},
+		{"ref:file-1:18-22", 18, 22, []int{20}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d excerpts, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		e := got[i]
+		if e.ChunkID != w.id || e.StartLine != w.start || e.EndLine != w.end || fmt.Sprint(e.ReferencedLines) != fmt.Sprint(w.refs) {
+			t.Errorf("excerpt %d = %s lines %d-%d refs %v, want %s lines %d-%d refs %v",
+				i, e.ChunkID, e.StartLine, e.EndLine, e.ReferencedLines, w.id, w.start, w.end, w.refs)
+		}
+		if first := strings.SplitN(e.Content, "\n", 2)[0]; first != lines[w.start-1] {
+			t.Errorf("excerpt %d starts with %q, want %q", i, first, lines[w.start-1])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+)
+
+// Reranker reorders retrieval candidates by relevance to the query. It sets
+// RerankScore on every candidate and returns them best first.
+type Reranker interface {
+	Name() string
+	Rerank(ctx context.Context, query string, candidates []models.ChunkSearchResult) ([]models.ChunkSearchResult, error)
+}
+
+// sortByRerankScore orders candidates by descending RerankScore, keeping
+// the fused order for ties.
+func sortByRerankScore(candidates []models.ChunkSearchResult) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return *candidates[i].RerankScore > *candidates[j].RerankScore
+	})
+}
+
+// lexicalFusionK is the RRF constant LexicalReranker blends orders with.
+const lexicalFusionK = 60
+
+// LexicalReranker scores candidates with BM25, using the candidate pool
+// itself as the corpus, and blends the BM25 order with the incoming fused
+// order by RRF so it refines the hybrid ranking rather than replacing it.
+// Identifiers are split into their parts so that "hybrid search" matches
+// HybridSearch.
+type LexicalReranker struct {
+	k1 float64
+	b  float64
+}
+
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{k1: 1.2, b: 0.75}
+}
+
+func (r *LexicalReranker) Name() string { return "bm25" }
+
+func (r *LexicalReranker) Rerank(_ context.Context, query string, candidates []models.ChunkSearchResult) ([]models.ChunkSearchResult, error) {
+	queryTerms := uniqueTerms(lexicalTerms(query))
+
+	docs := make([]map[string]int, len(candidates))
+	docFreq := make(map[string]int)
+	totalLen := 0
+	for i, c := range candidates {
+		terms := lexicalTerms(c.FilePath + " " + c.SymbolName + " " + c.Content)
+		tf := make(map[string]int, len(terms))
+		for _, t := range terms {
+			tf[t]++
+		}
+		for t := range tf {
+			docFreq[t]++
+		}
+		docs[i] = tf
+		totalLen += len(terms)
+	}
+	avgLen := float64(totalLen) / math.Max(float64(len(candidates)), 1)
+	n := float64(len(candidates))
+
+	bm25 := make([]float64, len(candidates))
+	for i := range candidates {
+		docLen := 0
+		for _, c := range docs[i] {
+			docLen += c
+		}
+		score := 0.0
+		for _, t := range queryTerms {
+			tf := float64(docs[i][t])
+			if tf == 0 {
+				continue
+			}
+			df := float64(docFreq[t])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (r.k1 + 1) / (tf + r.k1*(1-r.b+r.b*float64(docLen)/math.Max(avgLen, 1)))
+		}
+		bm25[i] = score
+	}
+
+	// Candidates arrive in fused order; chunks without a BM25 match get no
+	// share of the lexical list
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return bm25[order[a]] > bm25[order[b]] })
+	lexicalRank := make([]int, len(candidates))
+	for rank, i := range order {
+		lexicalRank[i] = rank
+	}
+	for i := range candidates {
+		score := 1 / float64(lexicalFusionK+i+1)
+		if bm25[i] > 0 {
+			score += 1 / float64(lexicalFusionK+lexicalRank[i]+1)
+		}
+		candidates[i].RerankScore = &score
+	}
+
+	sortByRerankScore(candidates)
+	return candidates, nil
+}
+
+// lexicalTerms lower-cases text into word terms, adding the parts of
+// camelCase and snake_case identifiers.
+func lexicalTerms(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		terms = append(terms, lower)
+		for _, part := range splitIdentifier(w) {
+			if part != lower && part != "" {
+				terms = append(terms, part)
+			}
+		}
+	}
+	return terms
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// LLMReranker asks the chat model to grade each candidate's relevance to the
+// query on a 0-10 scale.
+type LLMReranker struct {
+	chatModel ChatModel
+	model     string
+}
+
+func NewLLMReranker(chatModel ChatModel, model string) *LLMReranker {
+	return &LLMReranker{chatModel: chatModel, model: model}
+}
+
+func (r *LLMReranker) Name() string { return "llm" }
+
+var jsonArrayPattern = regexp.MustCompile(`(?s)\[.*\]`)
+
+func (r *LLMReranker) Rerank(ctx context.Context, query string, candidates []models.ChunkSearchResult) ([]models.ChunkSearchResult, error) {
+	var sb strings.Builder
+	for i, c := range candidates {
+		fmt.Fprintf(&sb, "<passage id=\"%d\" source=\"%s\">\n%s\n</passage>\n", i+1, sourceLabel(c), truncateRunes(c.Content, 800))
+	}
+
+	temperature := float32(0)
+	reply, err := Complete(ctx, r.chatModel, ChatRequest{
+		Model: r.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: rag.RerankPrompt},
+			{Role: "user", Content: fmt.Sprintf("Question: %s\n\n%s", query, sb.String())},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("llm rerank: %w", err)
+	}
+
+	var grades []struct {
+		ID    int     `json:"id"`
+		Score float64 `json:"score"`
+	}
+	if err := json.Unmarshal([]byte(jsonArrayPattern.FindString(reply)), &grades); err != nil {
+		return nil, fmt.Errorf("llm rerank: parse grades: %w", err)
+	}
+
+	scores := make([]float64, len(candidates))
+	for _, g := range grades {
+		if g.ID >= 1 && g.ID <= len(candidates) {
+			scores[g.ID-1] = g.Score
+		}
+	}
+	for i := range candidates {
+		candidates[i].RerankScore = &scores[i]
+	}
+
+	sortByRerankScore(candidates)
+	return candidates, nil
+}
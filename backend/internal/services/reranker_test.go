@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"rag-chat-system/internal/models"
+)
+
+func TestLexicalRerankerRefinesFusedOrder(t *testing.T) {
+	// Candidates arrive in fused order
+	candidates := []models.ChunkSearchResult{
+		{ChunkID: "fused-top", Content: "idle tokens expire after a while"},
+		{ChunkID: "unrelated", Content: "upload handlers stream files to storage"},
+		{ChunkID: "lexical-top", Content: "session expiry: sessions expire after thirty minutes"},
+	}
+
+	got, err := NewLexicalReranker().Rerank(context.Background(), "when do sessions expire", candidates)
+	if err != nil {
+		t.Fatalf("rerank: %v", err)
+	}
+
+	var order []string
+	for _, c := range got {
+		if c.RerankScore == nil {
+			t.Fatalf("chunk %s has no rerank score", c.ChunkID)
+		}
+		order = append(order, c.ChunkID)
+	}
+	want := []string{"fused-top", "lexical-top", "unrelated"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order %v, want %v", order, want)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+)
+
+const (
+	// summaryInputTokens bounds the file content or child summaries one
+	// summary is generated from.
+	summaryInputTokens = 3000
+	// summaryWorkers bounds concurrent summary requests per project.
+	summaryWorkers = 4
+	// repoMapTokens bounds the repo map of one project.
+	repoMapTokens = 1500
+	// minRepoMapTokens is the smallest share of the budget worth a project's
+	// map; with more projects than that allows, no map is rendered.
+	minRepoMapTokens = 200
+)
+
+// SummaryService has the chat model summarize the files and directories of
+// a project, stores the summaries as embedded chunks and renders them into a
+// repo map.
+type SummaryService struct {
+	chunkRepo        *repositories.ChunkRepo
+	fileRepo         *repositories.FileRepo
+	projectRepo      *repositories.ProjectRepo
+	cacheRepo        *repositories.SummaryCacheRepo
+	embeddingService *EmbeddingService
+	embeddingCache   *EmbeddingCacheService
+	chatModel        ChatModel
+	model            string
+
+	mu   sync.Mutex
+	runs map[string]bool // projects being summarized -> whether to run again
+}
+
+func NewSummaryService(
+	chunkRepo *repositories.ChunkRepo,
+	fileRepo *repositories.FileRepo,
+	projectRepo *repositories.ProjectRepo,
+	cacheRepo *repositories.SummaryCacheRepo,
+	embeddingService *EmbeddingService,
+	embeddingCache *EmbeddingCacheService,
+	chatModel ChatModel,
+	model string,
+) *SummaryService {
+	return &SummaryService{
+		chunkRepo:        chunkRepo,
+		fileRepo:         fileRepo,
+		projectRepo:      projectRepo,
+		cacheRepo:        cacheRepo,
+		embeddingService: embeddingService,
+		embeddingCache:   embeddingCache,
+		chatModel:        chatModel,
+		model:            model,
+		runs:             make(map[string]bool),
+	}
+}
+
+// SummarizeAsync runs Summarize in the background. If the project is
+// already being summarized, it is summarized once more afterwards to pick
+// up files added in the meantime.
+func (s *SummaryService) SummarizeAsync(projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, running := s.runs[projectID]; running {
+		s.runs[projectID] = true
+		return
+	}
+	s.runs[projectID] = false
+
+	go func() {
+		for {
+			if err := s.Summarize(context.Background(), projectID); err != nil {
+				log.Printf("[Summary] Project %s failed: %v", projectID, err)
+			}
+			s.mu.Lock()
+			if !s.runs[projectID] {
+				delete(s.runs, projectID)
+				s.mu.Unlock()
+				return
+			}
+			s.runs[projectID] = false
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// Summarize brings a project's summaries up to date, if its settings ask
+// for them. Files without a summary are summarized from their chunks; since
+// re-ingesting a file drops its summary, that covers changed files too.
+// Directories are then summarized deepest first from the summaries of their
+// children, and only when those changed. Files that fail are logged and
+// skipped.
+func (s *SummaryService) Summarize(ctx context.Context, projectID string) error {
+	settings, err := s.projectRepo.GetSettings(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("project settings: %w", err)
+	}
+	if !settings.Summaries {
+		return nil
+	}
+
+	todo, err := s.chunkRepo.ListUnsummarizedFiles(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.chunkRepo.ListSummaries(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	// Summaries embedded by another model are stored again; their text
+	// comes from the cache
+	for _, c := range existing {
+		if c.ChunkType == models.ChunkTypeFileSummary && c.EmbeddingModel != s.embeddingService.Model() {
+			todo[c.FileID] = c.FilePath
+		}
+	}
+
+	var mu sync.Mutex
+	fileSummaries := make(map[string]string) // path -> summary
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(summaryWorkers)
+	for fileID, filePath := range todo {
+		g.Go(func() error {
+			summary, err := s.summarizeFile(gctx, projectID, fileID, filePath, settings.FTSLanguage)
+			if err != nil {
+				log.Printf("[Summary] Skipping %s: %v", filePath, err)
+				return nil
+			}
+			mu.Lock()
+			fileSummaries[filePath] = summary
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	if len(todo) > 0 {
+		log.Printf("[Summary] Summarized %d/%d files of project %s", len(fileSummaries), len(todo), projectID)
+	}
+
+	dirSummaries := make(map[string]models.DocumentChunk) // path -> summary
+	for _, c := range existing {
+		switch c.ChunkType {
+		case models.ChunkTypeFileSummary:
+			if _, ok := fileSummaries[c.FilePath]; !ok {
+				fileSummaries[c.FilePath] = summaryText(c.Content)
+			}
+		case models.ChunkTypeDirSummary:
+			dirSummaries[c.FilePath] = c
+		}
+	}
+	return s.summarizeDirs(ctx, projectID, settings.FTSLanguage, fileSummaries, dirSummaries)
+}
+
+// summarizeFile summarizes one file from its content chunks and stores the
+// summary, returning its text.
+func (s *SummaryService) summarizeFile(ctx context.Context, projectID, fileID, filePath, ftsLanguage string) (string, error) {
+	chunks, err := s.chunkRepo.ListWindows(ctx, []repositories.NeighborWindow{{FileID: fileID, From: 0, To: math.MaxInt32}})
+	if err != nil {
+		return "", err
+	}
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("no chunks")
+	}
+	content := mergeWindow(chunks[0], chunks).Content
+	input := fmt.Sprintf("File: %s\n\n%s", filePath, rag.TruncateTokens(content, summaryInputTokens))
+
+	summary, err := s.generate(ctx, rag.FileSummaryPrompt, input)
+	if err != nil {
+		return "", err
+	}
+	return summary, s.store(ctx, projectID, fileID, filePath, models.ChunkTypeFileSummary, summary, "", ftsLanguage)
+}
+
+// summarizeDirs summarizes every directory of a project with summarized
+// children, deepest first so subdirectory summaries feed their parents.
+// A directory keeps its summary while the hash of its children's summaries
+// and the embedding model are unchanged; one left without summarized
+// children loses it.
+func (s *SummaryService) summarizeDirs(ctx context.Context, projectID, ftsLanguage string, files map[string]string, existing map[string]models.DocumentChunk) error {
+	all, err := s.fileRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+	byDepth := make(map[int][]models.File)
+	maxDepth := 0
+	for _, f := range all {
+		if !f.IsDir {
+			continue
+		}
+		depth := strings.Count(f.Path, "/")
+		byDepth[depth] = append(byDepth[depth], f)
+		maxDepth = max(maxDepth, depth)
+	}
+
+	children := make(map[string][]string) // dir -> "path: summary" lines
+	for p, summary := range files {
+		dir := path.Dir(p)
+		children[dir] = append(children[dir], fmt.Sprintf("%s: %s", path.Base(p), oneLine(summary)))
+	}
+
+	var mu sync.Mutex
+	for depth := maxDepth; depth >= 0; depth-- {
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(summaryWorkers)
+		for _, dir := range byDepth[depth] {
+			mu.Lock()
+			lines := children[dir.Path]
+			mu.Unlock()
+			old, hasOld := existing[dir.Path]
+			if len(lines) == 0 {
+				if hasOld {
+					if err := s.chunkRepo.DeleteSummary(ctx, dir.ID, models.ChunkTypeDirSummary); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			sort.Strings(lines)
+			input := fmt.Sprintf("Directory: %s\n\n%s", dir.Path, rag.TruncateTokens(strings.Join(lines, "\n"), summaryInputTokens))
+			sourceHash := ContentHash(s.model, input)
+
+			g.Go(func() error {
+				summary := summaryText(old.Content)
+				if !hasOld || old.SourceHash != sourceHash || old.EmbeddingModel != s.embeddingService.Model() {
+					var err error
+					summary, err = s.generate(gctx, rag.DirSummaryPrompt, input)
+					if err == nil {
+						err = s.store(gctx, projectID, dir.ID, dir.Path, models.ChunkTypeDirSummary, summary, sourceHash, ftsLanguage)
+					}
+					if err != nil {
+						log.Printf("[Summary] Skipping directory %s: %v", dir.Path, err)
+						return nil
+					}
+				}
+				mu.Lock()
+				parent := path.Dir(dir.Path)
+				children[parent] = append(children[parent], fmt.Sprintf("%s/: %s", path.Base(dir.Path), oneLine(summary)))
+				mu.Unlock()
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generate returns the summary the chat model writes for input under
+// prompt, from the cache when the same input was summarized before.
+func (s *SummaryService) generate(ctx context.Context, prompt, input string) (string, error) {
+	hash := ContentHash(s.model, prompt+"\x00"+input)
+	if summary, ok, err := s.cacheRepo.Get(ctx, hash); err != nil {
+		// A broken cache must not block summarizing
+		log.Printf("[Summary] Cache lookup failed: %v", err)
+	} else if ok {
+		return summary, nil
+	}
+
+	temperature := float32(0.2)
+	reply, err := Complete(ctx, s.chatModel, ChatRequest{
+		Model: s.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: input},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", fmt.Errorf("generate summary: %w", err)
+	}
+	summary := strings.TrimSpace(reply)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	if err := s.cacheRepo.Put(ctx, hash, summary); err != nil {
+		log.Printf("[Summary] Cache store failed: %v", err)
+	}
+	return summary, nil
+}
+
+// store embeds a summary and saves it as the summary chunk of a file or
+// directory. The chunk's content names what it summarizes, so the path
+// takes part in both the embedding and the full-text index.
+func (s *SummaryService) store(ctx context.Context, projectID, fileID, filePath, chunkType, summary, sourceHash, ftsLanguage string) error {
+	kind := "file"
+	if chunkType == models.ChunkTypeDirSummary {
+		kind = "directory"
+	}
+	content := fmt.Sprintf("Summary of %s %s:\n\n%s", kind, filePath, summary)
+	embeddings, hashes, err := s.embeddingCache.Embed(ctx, s.embeddingService.Model(), []string{content}, s.embeddingService.CreateEmbeddings)
+	if err != nil {
+		return fmt.Errorf("create embedding: %w", err)
+	}
+
+	c := &models.DocumentChunk{
+		ID:          uuid.New().String(),
+		ProjectID:   projectID,
+		FileID:      fileID,
+		FilePath:    filePath,
+		FileName:    path.Base(filePath),
+		Content:     content,
+		ContentHash: hashes[0],
+		SearchText:  rag.SearchText(content),
+		Embedding:   embeddings[0],
+		ChunkType:   chunkType,
+		SourceHash:  sourceHash,
+
+		EmbeddingModel: s.embeddingService.Model(),
+	}
+	if chunkType == models.ChunkTypeFileSummary {
+		c.FileExt = filepath.Ext(c.FileName)
+	}
+	if err := s.chunkRepo.ReplaceSummary(ctx, c, ftsLanguage); err != nil {
+		return fmt.Errorf("store summary: %w", err)
+	}
+	return nil
+}
+
+// RepoMap renders a map of each project's files and directories with their
+// summaries within maxTokens, split evenly across the projects that have
+// files. It returns "" if none has files, or if so many do that a project's
+// share would be below minRepoMapTokens. No projects means all of them, as in
+// retrieval.
+func (s *SummaryService) RepoMap(ctx context.Context, projectIDs []string, maxTokens int) (string, error) {
+	projectIDs, err := s.projectRepo.ResolveIDs(ctx, projectIDs)
+	if err != nil {
+		return "", fmt.Errorf("list projects: %w", err)
+	}
+	type projectFiles struct {
+		id    string
+		files []models.File
+	}
+	var mapped []projectFiles
+	for _, projectID := range projectIDs {
+		files, err := s.fileRepo.ListByProject(ctx, projectID)
+		if err != nil {
+			return "", fmt.Errorf("list files: %w", err)
+		}
+		if len(files) > 0 {
+			mapped = append(mapped, projectFiles{projectID, files})
+		}
+	}
+	if len(mapped) == 0 {
+		return "", nil
+	}
+	perProject := min(repoMapTokens, maxTokens/len(mapped))
+	if perProject < minRepoMapTokens {
+		return "", nil
+	}
+
+	var maps []string
+	for _, p := range mapped {
+		project, err := s.projectRepo.GetByID(ctx, p.id)
+		if err != nil {
+			return "", fmt.Errorf("get project: %w", err)
+		}
+		summaries, err := s.chunkRepo.ListSummaries(ctx, p.id)
+		if err != nil {
+			return "", err
+		}
+		byFile := make(map[string]string, len(summaries))
+		for _, c := range summaries {
+			byFile[c.FileID] = summaryText(c.Content)
+		}
+
+		paths := relativePaths(p.files)
+		entries := make([]rag.RepoMapEntry, len(p.files))
+		for i, f := range p.files {
+			entries[i] = rag.RepoMapEntry{Path: paths[f.ID], IsDir: f.IsDir, Summary: byFile[f.ID]}
+		}
+		maps = append(maps, fmt.Sprintf("Project %s:\n%s", project.Name, rag.RenderRepoMap(entries, perProject)))
+	}
+	return strings.Join(maps, "\n\n"), nil
+}
+
+// summaryText strips the heading store puts before a summary.
+func summaryText(content string) string {
+	if _, text, ok := strings.Cut(content, "\n\n"); ok {
+		return text
+	}
+	return content
+}
+
+// oneLine joins the lines of s with spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
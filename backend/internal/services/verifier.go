@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"rag-chat-system/internal/models"
+	"rag-chat-system/internal/rag"
+	"rag-chat-system/internal/repositories"
+)
+
+// Verifier checks a streamed answer for claims its context does not support
+// and for files and identifiers that do not exist in the project.
+type Verifier struct {
+	chatModel   ChatModel
+	model       string
+	fileRepo    *repositories.FileRepo
+	chunkRepo   *repositories.ChunkRepo
+	symbolRepo  *repositories.SymbolRepo
+	projectRepo *repositories.ProjectRepo
+}
+
+func NewVerifier(chatModel ChatModel, model string, fileRepo *repositories.FileRepo, chunkRepo *repositories.ChunkRepo, symbolRepo *repositories.SymbolRepo, projectRepo *repositories.ProjectRepo) *Verifier {
+	return &Verifier{chatModel: chatModel, model: model, fileRepo: fileRepo, chunkRepo: chunkRepo, symbolRepo: symbolRepo, projectRepo: projectRepo}
+}
+
+// Verify checks answer against the context entries it was written from
+// (see RAGService.BuildContext) and the files, symbols and content of the
+// given projects, or of all projects if none are given. A failed claim check
+// is reported in the result's Error; an error is only returned when the name
+// checks fail.
+func (v *Verifier) Verify(ctx context.Context, answer string, entries []string, projectIDs []string) (*models.Verification, error) {
+	result := &models.Verification{Claims: []models.ClaimCheck{}, UnknownFiles: []string{}, UnknownIdentifiers: []string{}}
+
+	projectIDs, err := v.projectRepo.ResolveIDs(ctx, projectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	if len(projectIDs) > 0 {
+		files, identifiers := rag.AnswerReferences(answer)
+		unknownFiles, err := v.unknownFiles(ctx, files, projectIDs)
+		if err != nil {
+			return nil, err
+		}
+		unknownIdents, err := v.unknownIdentifiers(ctx, identifiers, strings.Join(entries, "\n"), projectIDs)
+		if err != nil {
+			return nil, err
+		}
+		result.UnknownFiles = append(result.UnknownFiles, unknownFiles...)
+		result.UnknownIdentifiers = append(result.UnknownIdentifiers, unknownIdents...)
+	}
+
+	if len(entries) > 0 {
+		claims, err := v.checkClaims(ctx, answer, entries)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Claims = claims
+			for _, c := range claims {
+				if !c.Supported {
+					result.Unsupported++
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// unknownFiles returns the paths no file of the projects ends with. Nothing
+// is reported for projects without files.
+func (v *Verifier) unknownFiles(ctx context.Context, refs, projectIDs []string) ([]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	// Every trailing run of segments of every path, so a reference is
+	// looked up once instead of compared with each path
+	suffixes := make(map[string]bool)
+	for _, projectID := range projectIDs {
+		files, err := v.fileRepo.ListByProject(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("list files: %w", err)
+		}
+		for _, p := range relativePaths(files) {
+			p = "/" + p
+			for i := strings.LastIndex(p, "/"); i >= 0; i = strings.LastIndex(p[:i], "/") {
+				suffixes[p[i+1:]] = true
+			}
+		}
+	}
+	if len(suffixes) == 0 {
+		return nil, nil
+	}
+
+	var unknown []string
+	for _, ref := range refs {
+		if !suffixes[ref] {
+			unknown = append(unknown, ref)
+		}
+	}
+	return unknown, nil
+}
+
+// unknownIdentifiers returns the identifiers that are neither declared in
+// the projects' symbol index nor found in the context or the projects'
+// content. A qualified name ("fmt.Println") is only checked when its
+// qualifier is known, since otherwise it likely names another package.
+func (v *Verifier) unknownIdentifiers(ctx context.Context, identifiers []string, contextText string, projectIDs []string) ([]string, error) {
+	names := make(map[string]bool)
+	for _, id := range identifiers {
+		for _, part := range strings.Split(id, ".") {
+			names[part] = true
+		}
+	}
+	var lookup []string
+	known := make(map[string]bool)
+	for name := range names {
+		if strings.Contains(contextText, name) {
+			known[name] = true
+		} else {
+			lookup = append(lookup, name)
+		}
+	}
+	if len(lookup) > 0 {
+		declared, err := v.symbolRepo.ExistingNames(ctx, projectIDs, lookup)
+		if err != nil {
+			return nil, err
+		}
+		var rest []string
+		for _, name := range lookup {
+			if declared[name] {
+				known[name] = true
+			} else {
+				rest = append(rest, name)
+			}
+		}
+		if len(rest) > 0 {
+			found, err := v.chunkRepo.ContainingTerms(ctx, projectIDs, rest)
+			if err != nil {
+				return nil, err
+			}
+			for name := range found {
+				known[name] = true
+			}
+		}
+	}
+
+	var unknown []string
+	for _, id := range identifiers {
+		parts := strings.Split(id, ".")
+		qualifiers, name := parts[:len(parts)-1], parts[len(parts)-1]
+		external := false
+		for _, q := range qualifiers {
+			if !known[q] {
+				external = true
+			}
+		}
+		if !external && !known[name] {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown, nil
+}
+
+// checkClaims has the chat model split the answer into claims and judge
+// each against the numbered context entries.
+func (v *Verifier) checkClaims(ctx context.Context, answer string, entries []string) ([]models.ClaimCheck, error) {
+	temperature := float32(0)
+	reply, err := Complete(ctx, v.chatModel, ChatRequest{
+		Model: v.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: rag.VerifyPrompt},
+			{Role: "user", Content: fmt.Sprintf("Context:\n\n%s\n\nAnswer:\n\n%s", strings.Join(entries, rag.ContextSeparator), answer)},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify claims: %w", err)
+	}
+	var claims []models.ClaimCheck
+	if err := json.Unmarshal([]byte(jsonArrayPattern.FindString(reply)), &claims); err != nil {
+		return nil, fmt.Errorf("verify claims: parse reply: %w", err)
+	}
+	return claims, nil
+}
@@ -261,7 +261,7 @@ export default function ChatWindow({
           }}>
             <div style={{ maxWidth: 768, margin: '0 auto' }}>
               {messages.map(msg => (
-                <MessageBubble key={msg.id} role={msg.role} content={msg.content} />
+                <MessageBubble key={msg.id} role={msg.role} content={msg.content} citations={msg.citations} />
               ))}
               {streaming && streamingContent && (
                 <MessageBubble role="assistant" content={streamingContent} />
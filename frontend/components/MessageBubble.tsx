@@ -5,10 +5,12 @@ import ReactMarkdown from 'react-markdown'
 import remarkGfm from 'remark-gfm'
 import { Prism as SyntaxHighlighter } from 'react-syntax-highlighter'
 import { oneDark } from 'react-syntax-highlighter/dist/cjs/styles/prism'
+import type { Citation } from '@/lib/api'
 
 interface Props {
   role: string
   content: string
+  citations?: Citation[]
 }
 
 function CopyButton({ text }: { text: string }) {
@@ -237,7 +239,25 @@ function MarkdownContent({ content }: { content: string }) {
   )
 }
 
-export default function MessageBubble({ role, content }: Props) {
+function Sources({ citations }: { citations: Citation[] }) {
+  return (
+    <div style={{
+      marginTop: 10,
+      paddingTop: 8,
+      borderTop: '1px solid #333',
+      fontSize: 12,
+      color: '#888',
+    }}>
+      {citations.map(c => (
+        <div key={c.marker} style={{ fontFamily: 'monospace', margin: '2px 0' }}>
+          <span style={{ color: '#d4a574' }}>[{c.marker}]</span> {c.file_path || c.file_name}
+        </div>
+      ))}
+    </div>
+  )
+}
+
+export default function MessageBubble({ role, content, citations }: Props) {
   const isUser = role === 'user'
 
   if (isUser) {
@@ -298,6 +318,7 @@ export default function MessageBubble({ role, content }: Props) {
           overflow: 'hidden',
         }}>
           <MarkdownContent content={content} />
+          {citations && citations.length > 0 && <Sources citations={citations} />}
         </div>
       </div>
     </div>
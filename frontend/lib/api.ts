@@ -9,6 +9,29 @@ export interface Project {
   git_branch?: string;
   last_synced_at?: string;
   created_at: string;
+  settings: ProjectSettings;
+}
+
+export interface ProjectSettings {
+  chunk_size: number;
+  chunk_overlap: number;
+  chunker: 'auto' | 'code' | 'text';
+  fts_language: string;
+  summaries: boolean;
+  top_k: number;
+  vector_weight: number;
+  fts_weight: number;
+  exact_weight: number;
+  symbol_weight: number;
+  rrf_k: number;
+  neighbor_chunks: number;
+  import_neighbors: number;
+  ref_window: number;
+  mmr_lambda: number;
+  max_chunks_per_file: number;
+  min_similarity: number;
+  min_score: number;
+  low_confidence_similarity: number;
 }
 
 export interface GitConfig {
@@ -31,21 +54,62 @@ export interface FileNode {
   children?: FileNode[];
 }
 
+export type RetrievalStrategy = 'single' | 'multi_query' | 'hyde';
+
 export interface Chat {
   id: string;
   title: string;
   project_ids: string[];
+  model: string;
+  temperature?: number;
+  query_rewrite: boolean;
+  retrieval_strategy: RetrievalStrategy;
+  verify: boolean;
   created_at: string;
 }
 
+export interface SearchFilter {
+  paths?: string[];
+  exts?: string[];
+  file_ids?: string[];
+  exclude_paths?: string[];
+  exclude_exts?: string[];
+}
+
+export interface Citation {
+  marker: number;
+  chunk_id: string;
+  file_id: string;
+  file_path: string;
+  file_name: string;
+}
+
 export interface Message {
   id: string;
   chat_id: string;
   role: string;
   content: string;
+  citations?: Citation[];
+  rewritten_query?: string;
+  verification?: Verification;
   created_at: string;
 }
 
+export interface ClaimCheck {
+  claim: string;
+  supported: boolean;
+  markers?: number[];
+  reason?: string;
+}
+
+export interface Verification {
+  claims: ClaimCheck[];
+  unsupported: number;
+  unknown_files: string[];
+  unknown_identifiers: string[];
+  error?: string;
+}
+
 export async function getProjects(): Promise<Project[]> {
   const res = await fetch(`${API_BASE}/projects`);
   return res.json();
@@ -100,6 +164,35 @@ export async function deleteFile(fileId: string): Promise<void> {
   await fetch(`${API_BASE}/files/${fileId}`, { method: 'DELETE' });
 }
 
+export async function getProjectSettings(projectId: string): Promise<ProjectSettings> {
+  const res = await fetch(`${API_BASE}/projects/${projectId}/settings`);
+  return res.json();
+}
+
+export async function updateProjectSettings(
+  projectId: string,
+  settings: ProjectSettings,
+): Promise<{ settings: ProjectSettings; reindex_required: boolean }> {
+  const res = await fetch(`${API_BASE}/projects/${projectId}/settings`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(settings),
+  });
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({ error: 'Save failed' }));
+    throw new Error(data.error || 'Save failed');
+  }
+  return res.json();
+}
+
+export async function reindexProject(projectId: string): Promise<void> {
+  const res = await fetch(`${API_BASE}/projects/${projectId}/reindex`, { method: 'POST' });
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({ error: 'Reindex failed' }));
+    throw new Error(data.error || 'Reindex failed');
+  }
+}
+
 // Git sync API
 
 export async function getGitConfig(projectId: string): Promise<GitConfig | null> {
@@ -162,6 +255,29 @@ export async function createChat(title?: string, projectIds?: string[]): Promise
   return res.json();
 }
 
+export async function updateChat(
+  chatId: string,
+  update: {
+    title?: string;
+    model?: string;
+    temperature?: number;
+    query_rewrite?: boolean;
+    retrieval_strategy?: RetrievalStrategy;
+    verify?: boolean;
+  },
+): Promise<Chat> {
+  const res = await fetch(`${API_BASE}/chats/${chatId}`, {
+    method: 'PATCH',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(update),
+  });
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({ error: 'Update failed' }));
+    throw new Error(data.error || 'Update failed');
+  }
+  return res.json();
+}
+
 export async function updateChatProjects(chatId: string, projectIds: string[]): Promise<void> {
   await fetch(`${API_BASE}/chats/${chatId}/projects`, {
     method: 'PUT',
@@ -182,13 +298,16 @@ export function sendMessage(
   onToken: (token: string) => void,
   onDone: () => void,
   onError: (err: string) => void,
+  onCitations?: (citations: Citation[]) => void,
+  filter?: SearchFilter,
+  onVerification?: (verification: Verification) => void,
 ): AbortController {
   const controller = new AbortController();
 
   fetch(`${API_BASE}/chats/${chatId}/messages`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ message, project_ids: projectIds }),
+    body: JSON.stringify({ message, project_ids: projectIds, filter }),
     signal: controller.signal,
   })
     .then(async (res) => {
@@ -217,6 +336,14 @@ export function sendMessage(
               onError(data);
               return;
             }
+            if (data.startsWith('[CITATIONS] ')) {
+              onCitations?.(JSON.parse(data.slice(12)));
+              continue;
+            }
+            if (data.startsWith('[VERIFICATION] ')) {
+              onVerification?.(JSON.parse(data.slice(15)));
+              continue;
+            }
             onToken(data);
           }
         }